@@ -1,6 +1,8 @@
 package cache_test
 
 import (
+	"context"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/wazofski/gostorz/generated"
 	"github.com/wazofski/gostorz/memory"
 	"github.com/wazofski/gostorz/store"
+	"github.com/wazofski/gostorz/store/options"
 )
 
 func TestCache(t *testing.T) {
@@ -20,9 +23,72 @@ func TestCache(t *testing.T) {
 var mainst store.Store
 var cached store.Store
 
+var backend *countingStore
+var counted store.Store
+
 var _ = BeforeSuite(func() {
 	sch := generated.Schema()
 
 	mainst = store.New(sch, memory.Factory())
 	cached = store.New(sch, cache.Factory(mainst, 1*time.Second))
+
+	backend = &countingStore{Store: store.New(sch, memory.Factory())}
+	counted = store.New(sch, cache.Factory(backend, 10*time.Minute))
 })
+
+// countingStore counts the Get and List calls reaching the backing store
+type countingStore struct {
+	store.Store
+	Gets  int32
+	Lists int32
+}
+
+func (d *countingStore) Get(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.GetOption) (store.Object, error) {
+
+	atomic.AddInt32(&d.Gets, 1)
+	time.Sleep(10 * time.Millisecond)
+
+	return d.Store.Get(ctx, identity, opt...)
+}
+
+func (d *countingStore) Calls() int {
+	return int(atomic.LoadInt32(&d.Gets))
+}
+
+func (d *countingStore) List(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.ListOption) (store.ObjectList, error) {
+
+	atomic.AddInt32(&d.Lists, 1)
+	time.Sleep(10 * time.Millisecond)
+
+	return d.Store.List(ctx, identity, opt...)
+}
+
+func (d *countingStore) ListCalls() int {
+	return int(atomic.LoadInt32(&d.Lists))
+}
+
+// gatedStore holds the Get calls reaching the backing store,
+// after reading the object, until the gate opens
+type gatedStore struct {
+	store.Store
+	Read chan struct{}
+	Gate chan struct{}
+}
+
+func (d *gatedStore) Get(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.GetOption) (store.Object, error) {
+
+	ret, err := d.Store.Get(ctx, identity, opt...)
+	d.Read <- struct{}{}
+	<-d.Gate
+
+	return ret, err
+}
@@ -2,12 +2,19 @@ package cache_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/wazofski/gostorz/cache"
 	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/memory"
+	"github.com/wazofski/gostorz/store"
+	"github.com/wazofski/gostorz/store/options"
 )
 
 var _ = Describe("cache", func() {
@@ -320,3 +327,674 @@ var _ = Describe("cache", func() {
 	})
 
 })
+
+var _ = Describe("cache reads", func() {
+
+	ctx := context.Background()
+
+	It("can serve fresh entries without the backing store", func() {
+		world := generated.WorldFactory()
+		world.External().SetName("counted")
+		ret, err := counted.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		calls := backend.Calls()
+		for i := 0; i < 5; i++ {
+			_, err = counted.Get(ctx, ret.Metadata().Identity())
+			Expect(err).To(BeNil())
+			_, err = counted.Get(ctx, generated.WorldIdentity("counted"))
+			Expect(err).To(BeNil())
+		}
+
+		Expect(backend.Calls()).To(Equal(calls))
+	})
+
+	It("can load missing entries once", func() {
+		world := generated.WorldFactory()
+		world.External().SetName("loaded")
+		_, err := backend.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		calls := backend.Calls()
+		wg := sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				_, err := counted.Get(ctx, generated.WorldIdentity("loaded"))
+				Expect(err).To(BeNil())
+			}()
+		}
+		wg.Wait()
+
+		Expect(backend.Calls()).To(Equal(calls + 1))
+	})
+
+	It("can cache not found results", func() {
+		calls := backend.Calls()
+		for i := 0; i < 3; i++ {
+			_, err := counted.Get(ctx, generated.WorldIdentity("missing"))
+			Expect(err).ToNot(BeNil())
+		}
+		Expect(backend.Calls()).To(Equal(calls + 1))
+
+		// creating through the cache replaces the negative entry
+		world := generated.WorldFactory()
+		world.External().SetName("missing")
+		_, err := counted.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		_, err = counted.Get(ctx, generated.WorldIdentity("missing"))
+		Expect(err).To(BeNil())
+		Expect(backend.Calls()).To(Equal(calls + 1))
+	})
+
+	It("can invalidate entries", func() {
+		ret, err := counted.Get(ctx, generated.WorldIdentity("counted"))
+		Expect(err).To(BeNil())
+
+		world := ret.(generated.World)
+		world.External().SetDescription("changed")
+		_, err = backend.Update(ctx, world.Metadata().Identity(), world)
+		Expect(err).To(BeNil())
+
+		ret, err = counted.Get(ctx, world.Metadata().Identity())
+		Expect(err).To(BeNil())
+		Expect(ret.(generated.World).External().Description()).To(Equal(""))
+
+		calls := backend.Calls()
+		err = cache.Invalidate(counted, generated.WorldIdentity("counted"))
+		Expect(err).To(BeNil())
+
+		// both the id and primary key paths are dropped
+		ret, err = counted.Get(ctx, world.Metadata().Identity())
+		Expect(err).To(BeNil())
+		Expect(ret.(generated.World).External().Description()).To(Equal("changed"))
+		Expect(backend.Calls()).To(Equal(calls + 1))
+
+		Expect(cache.Invalidate(mainst, world.Metadata().Identity())).ToNot(BeNil())
+	})
+
+	It("can drop entries on DELETE", func() {
+		err := counted.Delete(ctx, generated.WorldIdentity("counted"))
+		Expect(err).To(BeNil())
+
+		calls := backend.Calls()
+		_, err = counted.Get(ctx, generated.WorldIdentity("counted"))
+		Expect(err).ToNot(BeNil())
+		Expect(backend.Calls()).To(Equal(calls + 1))
+	})
+})
+
+var _ = Describe("cache lists", func() {
+
+	ctx := context.Background()
+
+	It("can serve repeated lists without the backing store", func() {
+		world := generated.WorldFactory()
+		world.External().SetName("listed")
+		_, err := counted.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		calls := backend.ListCalls()
+		for i := 0; i < 5; i++ {
+			ret, err := counted.List(ctx, generated.WorldIdentity(""),
+				options.OrderBy("external.name"))
+			Expect(err).To(BeNil())
+			Expect(len(ret)).To(BeNumerically(">", 0))
+		}
+		Expect(backend.ListCalls()).To(Equal(calls + 1))
+
+		// key filters are normalized
+		_, err = counted.List(ctx, generated.WorldIdentity(""),
+			options.KeyFilter("a", "b"))
+		Expect(err).To(BeNil())
+		_, err = counted.List(ctx, generated.WorldIdentity(""),
+			options.KeyFilter("b", "a"))
+		Expect(err).To(BeNil())
+		Expect(backend.ListCalls()).To(Equal(calls + 2))
+
+		// different options are different lists
+		_, err = counted.List(ctx, generated.WorldIdentity(""),
+			options.OrderBy("external.name"), options.OrderDescending())
+		Expect(err).To(BeNil())
+		Expect(backend.ListCalls()).To(Equal(calls + 3))
+	})
+
+	It("can load concurrent lists once", func() {
+		calls := backend.ListCalls()
+		wg := sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				_, err := counted.List(ctx, generated.WorldIdentity(""),
+					options.PageSize(2))
+				Expect(err).To(BeNil())
+			}()
+		}
+		wg.Wait()
+
+		Expect(backend.ListCalls()).To(Equal(calls + 1))
+	})
+
+	It("can invalidate lists on writes of the kind", func() {
+		list := func() int {
+			ret, err := counted.List(ctx, generated.WorldIdentity(""))
+			Expect(err).To(BeNil())
+			return len(ret)
+		}
+
+		count := list()
+		calls := backend.ListCalls()
+
+		world := generated.WorldFactory()
+		world.External().SetName("listed-new")
+		_, err := counted.Create(ctx, world)
+		Expect(err).To(BeNil())
+		Expect(list()).To(Equal(count + 1))
+		Expect(backend.ListCalls()).To(Equal(calls + 1))
+
+		// writes of other kinds keep the lists
+		second := generated.SecondWorldFactory()
+		second.External().SetName("listed-second")
+		_, err = counted.Create(ctx, second)
+		Expect(err).To(BeNil())
+		Expect(list()).To(Equal(count + 1))
+		Expect(backend.ListCalls()).To(Equal(calls + 1))
+
+		world.External().SetDescription("updated")
+		_, err = counted.Update(ctx, generated.WorldIdentity("listed-new"), world)
+		Expect(err).To(BeNil())
+		Expect(list()).To(Equal(count + 1))
+		Expect(backend.ListCalls()).To(Equal(calls + 2))
+
+		err = counted.Delete(ctx, generated.WorldIdentity("listed-new"))
+		Expect(err).To(BeNil())
+		Expect(list()).To(Equal(count))
+		Expect(backend.ListCalls()).To(Equal(calls + 3))
+	})
+
+	It("can expire lists", func() {
+		_, err := counted.List(ctx, generated.WorldIdentity(""),
+			options.PageSize(5), cache.Expire(100*time.Millisecond))
+		Expect(err).To(BeNil())
+
+		calls := backend.ListCalls()
+		_, err = counted.List(ctx, generated.WorldIdentity(""),
+			options.PageSize(5), cache.Expire(100*time.Millisecond))
+		Expect(err).To(BeNil())
+		Expect(backend.ListCalls()).To(Equal(calls))
+
+		time.Sleep(150 * time.Millisecond)
+		_, err = counted.List(ctx, generated.WorldIdentity(""),
+			options.PageSize(5), cache.Expire(100*time.Millisecond))
+		Expect(err).To(BeNil())
+		Expect(backend.ListCalls()).To(Equal(calls + 1))
+	})
+})
+
+var _ = Describe("cache limits", func() {
+
+	ctx := context.Background()
+
+	bounded := func(limits cache.Limits) (store.Store, *countingStore) {
+		sch := generated.Schema()
+		back := &countingStore{Store: store.New(sch, memory.Factory())}
+		st := store.New(sch, cache.BoundedFactory(back, limits, 10*time.Minute))
+
+		for _, name := range []string{"a", "b", "c"} {
+			world := generated.WorldFactory()
+			world.External().SetName(name)
+			_, err := back.Create(ctx, world)
+			Expect(err).To(BeNil())
+		}
+
+		return st, back
+	}
+
+	get := func(st store.Store, name string) {
+		_, err := st.Get(ctx, generated.WorldIdentity(name))
+		Expect(err).To(BeNil())
+	}
+
+	It("can evict the least recently used entries", func() {
+		st, back := bounded(cache.Limits{MaxEntries: 2})
+
+		get(st, "a")
+		get(st, "b")
+		get(st, "a")
+		get(st, "c")
+
+		calls := back.Calls()
+		get(st, "a")
+		get(st, "c")
+		Expect(back.Calls()).To(Equal(calls))
+
+		get(st, "b")
+		Expect(back.Calls()).To(Equal(calls + 1))
+
+		stats, err := cache.Stats(st)
+		Expect(err).To(BeNil())
+		Expect(stats.Entries).To(Equal(2))
+		Expect(stats.Evictions).To(Equal(uint64(2)))
+		Expect(stats.Hits).To(Equal(uint64(3)))
+		Expect(stats.Misses).To(Equal(uint64(4)))
+	})
+
+	It("can evict the least frequently used entries", func() {
+		st, back := bounded(cache.Limits{MaxEntries: 2, Eviction: cache.LFU})
+
+		get(st, "a")
+		get(st, "a")
+		get(st, "a")
+		get(st, "b")
+		get(st, "b")
+		get(st, "b")
+		get(st, "b")
+		get(st, "a")
+		get(st, "c")
+
+		calls := back.Calls()
+		get(st, "b")
+		get(st, "a")
+		Expect(back.Calls()).To(Equal(calls + 1))
+
+		stats, err := cache.Stats(st)
+		Expect(err).To(BeNil())
+		Expect(stats.Entries).To(Equal(2))
+		Expect(stats.Evictions).To(Equal(uint64(2)))
+	})
+
+	It("can bound the cached bytes", func() {
+		st, _ := bounded(cache.Limits{MaxBytes: 1})
+
+		get(st, "a")
+		stats, err := cache.Stats(st)
+		Expect(err).To(BeNil())
+		Expect(stats.Entries).To(Equal(0))
+		Expect(stats.Bytes).To(Equal(0))
+
+		st, _ = bounded(cache.Limits{MaxBytes: 1 << 20})
+		get(st, "a")
+		get(st, "b")
+		stats, err = cache.Stats(st)
+		Expect(err).To(BeNil())
+		Expect(stats.Entries).To(Equal(2))
+		Expect(stats.Bytes).To(BeNumerically(">", 0))
+		Expect(stats.Bytes).To(BeNumerically("<=", 1<<20))
+	})
+
+	It("can bound the cached lists", func() {
+		st, back := bounded(cache.Limits{MaxEntries: 1})
+
+		_, err := st.List(ctx, generated.WorldIdentity(""))
+		Expect(err).To(BeNil())
+		get(st, "a")
+
+		calls := back.ListCalls()
+		_, err = st.List(ctx, generated.WorldIdentity(""))
+		Expect(err).To(BeNil())
+		Expect(back.ListCalls()).To(Equal(calls + 1))
+	})
+
+	It("can keep only the expirations other than the default", func() {
+		st, _ := bounded(cache.Limits{MaxEntries: 2})
+		policies := func() int {
+			stats, err := cache.Stats(st)
+			Expect(err).To(BeNil())
+			return stats.Policies
+		}
+
+		get(st, "a")
+		world := generated.WorldFactory()
+		world.External().SetName("defaulted")
+		ret, err := st.Create(ctx, world)
+		Expect(err).To(BeNil())
+		_, err = st.Update(ctx, ret.Metadata().Identity(), ret)
+		Expect(err).To(BeNil())
+		Expect(policies()).To(Equal(0))
+
+		world = generated.WorldFactory()
+		world.External().SetName("expiring")
+		ret, err = st.Create(ctx, world, cache.Expire(time.Minute))
+		Expect(err).To(BeNil())
+		Expect(policies()).To(Equal(1))
+
+		Expect(cache.Invalidate(st, ret.Metadata().Identity())).To(Succeed())
+		Expect(policies()).To(Equal(0))
+
+		ret, err = st.Update(ctx, ret.Metadata().Identity(), ret, cache.Expire(time.Minute))
+		Expect(err).To(BeNil())
+		Expect(policies()).To(Equal(1))
+
+		// evicted by the next two entries
+		get(st, "b")
+		get(st, "c")
+		Expect(policies()).To(Equal(0))
+
+		_, err = st.Update(ctx, ret.Metadata().Identity(), ret, cache.Expire(time.Minute))
+		Expect(err).To(BeNil())
+		Expect(policies()).To(Equal(1))
+
+		Expect(st.Delete(ctx, ret.Metadata().Identity())).To(Succeed())
+		Expect(policies()).To(Equal(0))
+	})
+
+	It("cannot report stats of other stores", func() {
+		_, err := cache.Stats(mainst)
+		Expect(err).ToNot(BeNil())
+	})
+})
+
+var _ = Describe("cache revalidation", func() {
+
+	ctx := context.Background()
+
+	var st store.Store
+	var back *countingStore
+
+	BeforeEach(func() {
+		sch := generated.Schema()
+		back = &countingStore{Store: store.New(sch, memory.Factory())}
+		st = store.New(sch, cache.BoundedFactory(back,
+			cache.Limits{MaxStale: time.Minute}, 100*time.Millisecond))
+
+		world := generated.WorldFactory()
+		world.External().SetName("stale")
+		_, err := st.Create(ctx, world)
+		Expect(err).To(BeNil())
+	})
+
+	update := func(description string) {
+		ret, err := back.Get(ctx, generated.WorldIdentity("stale"))
+		Expect(err).To(BeNil())
+
+		world := ret.(generated.World)
+		world.External().SetDescription(description)
+		_, err = back.Update(ctx, world.Metadata().Identity(), world)
+		Expect(err).To(BeNil())
+	}
+
+	description := func(opt ...options.GetOption) string {
+		ret, err := st.Get(ctx, generated.WorldIdentity("stale"), opt...)
+		Expect(err).To(BeNil())
+		return ret.(generated.World).External().Description()
+	}
+
+	It("can serve stale entries while refreshing", func() {
+		update("refreshed")
+		time.Sleep(150 * time.Millisecond)
+
+		calls := back.Calls()
+		for i := 0; i < 5; i++ {
+			Expect(description(cache.StaleWhileRevalidate(time.Second))).To(Equal(""))
+		}
+
+		Eventually(func() string {
+			return description(cache.StaleWhileRevalidate(time.Second))
+		}).Should(Equal("refreshed"))
+		Expect(back.Calls()).To(Equal(calls + 1))
+
+		stats, err := cache.Stats(st)
+		Expect(err).To(BeNil())
+		Expect(stats.Stale).To(BeNumerically(">=", uint64(5)))
+	})
+
+	It("can refresh stale entries raced by writes of other objects", func() {
+		update("refreshed")
+		time.Sleep(150 * time.Millisecond)
+
+		calls := back.Calls()
+		Expect(description(cache.StaleWhileRevalidate(time.Minute))).To(Equal(""))
+
+		world := generated.WorldFactory()
+		world.External().SetName("unrelated")
+		_, err := st.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		Eventually(func() string {
+			return description(cache.StaleWhileRevalidate(time.Minute))
+		}).Should(Equal("refreshed"))
+		Expect(back.Calls()).To(Equal(calls + 1))
+	})
+
+	It("can cap the staleness at the limit of the cache", func() {
+		capped := store.New(generated.Schema(), cache.BoundedFactory(back,
+			cache.Limits{MaxStale: 50 * time.Millisecond}, 100*time.Millisecond))
+
+		_, err := capped.Get(ctx, generated.WorldIdentity("stale"))
+		Expect(err).To(BeNil())
+
+		update("refreshed")
+		time.Sleep(200 * time.Millisecond)
+
+		ret, err := capped.Get(ctx, generated.WorldIdentity("stale"),
+			cache.StaleWhileRevalidate(time.Hour))
+		Expect(err).To(BeNil())
+		Expect(ret.(generated.World).External().Description()).To(Equal("refreshed"))
+	})
+
+	It("can block beyond the max staleness", func() {
+		update("refreshed")
+		time.Sleep(250 * time.Millisecond)
+
+		calls := back.Calls()
+		Expect(description(cache.StaleWhileRevalidate(100 * time.Millisecond))).
+			To(Equal("refreshed"))
+		Expect(back.Calls()).To(Equal(calls + 1))
+	})
+
+	It("can block without the option", func() {
+		update("refreshed")
+		time.Sleep(150 * time.Millisecond)
+
+		Expect(description()).To(Equal("refreshed"))
+	})
+
+	It("can serve stale lists while refreshing", func() {
+		list := func() int {
+			ret, err := st.List(ctx, generated.WorldIdentity(""),
+				cache.StaleWhileRevalidate(time.Second))
+			Expect(err).To(BeNil())
+			return len(ret)
+		}
+
+		Expect(list()).To(Equal(1))
+
+		world := generated.WorldFactory()
+		world.External().SetName("stale-new")
+		_, err := back.Create(ctx, world)
+		Expect(err).To(BeNil())
+		time.Sleep(150 * time.Millisecond)
+
+		calls := back.ListCalls()
+		Expect(list()).To(Equal(1))
+		Eventually(list).Should(Equal(2))
+		Expect(back.ListCalls()).To(Equal(calls + 1))
+	})
+})
+
+var _ = Describe("cache races", func() {
+
+	ctx := context.Background()
+
+	var st store.Store
+	var back *gatedStore
+
+	BeforeEach(func() {
+		sch := generated.Schema()
+		back = &gatedStore{
+			Store: store.New(sch, memory.Factory()),
+			Read:  make(chan struct{}),
+			Gate:  make(chan struct{}),
+		}
+		st = store.New(sch, cache.Factory(back, time.Minute))
+
+		world := generated.WorldFactory()
+		world.External().SetName("raced")
+		_, err := back.Store.Create(ctx, world)
+		Expect(err).To(BeNil())
+	})
+
+	description := func() string {
+		ret, err := st.Get(ctx, generated.WorldIdentity("raced"))
+		Expect(err).To(BeNil())
+		return ret.(generated.World).External().Description()
+	}
+
+	It("cannot cache loads raced by writes", func() {
+		loaded := make(chan string)
+		go func() {
+			defer GinkgoRecover()
+			loaded <- description()
+		}()
+		<-back.Read
+
+		ret, err := back.Store.Get(ctx, generated.WorldIdentity("raced"))
+		Expect(err).To(BeNil())
+		world := ret.(generated.World)
+		world.External().SetDescription("updated")
+		_, err = st.Update(ctx, world.Metadata().Identity(), world)
+		Expect(err).To(BeNil())
+
+		close(back.Gate)
+		Expect(<-loaded).To(Equal(""))
+		Expect(description()).To(Equal("updated"))
+	})
+
+	It("cannot cache misses raced by creates", func() {
+		loaded := make(chan error)
+		go func() {
+			_, err := st.Get(ctx, generated.WorldIdentity("created"))
+			loaded <- err
+		}()
+		<-back.Read
+
+		world := generated.WorldFactory()
+		world.External().SetName("created")
+		_, err := st.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		close(back.Gate)
+		Expect(errors.Is(<-loaded, constants.ErrNoSuchObject)).To(BeTrue())
+
+		_, err = st.Get(ctx, generated.WorldIdentity("created"))
+		Expect(err).To(BeNil())
+	})
+
+	It("can leave shared loads to the other callers", func() {
+		cancelled, cancel := context.WithCancel(ctx)
+		first := make(chan error)
+		go func() {
+			_, err := st.Get(cancelled, generated.WorldIdentity("raced"))
+			first <- err
+		}()
+		<-back.Read
+
+		second := make(chan error)
+		go func() {
+			_, err := st.Get(ctx, generated.WorldIdentity("raced"))
+			second <- err
+		}()
+
+		cancel()
+		Expect(errors.Is(<-first, context.Canceled)).To(BeTrue())
+
+		close(back.Gate)
+		Expect(<-second).To(BeNil())
+	})
+
+	It("can sweep expired entries without limits", func() {
+		short := store.New(generated.Schema(),
+			cache.Factory(back.Store, 10*time.Millisecond))
+
+		create := func(prefix string) {
+			for i := 0; i < 100; i++ {
+				world := generated.WorldFactory()
+				world.External().SetName(fmt.Sprintf("%s%d", prefix, i))
+				_, err := short.Create(ctx, world)
+				Expect(err).To(BeNil())
+			}
+		}
+
+		create("swept")
+		time.Sleep(20 * time.Millisecond)
+		create("kept")
+
+		stats, err := cache.Stats(short)
+		Expect(err).To(BeNil())
+		Expect(stats.Entries).To(BeNumerically("<=", 100))
+	})
+})
+
+var _ = Describe("cache transactions", func() {
+
+	ctx := context.Background()
+
+	It("can invalidate the objects written in transactions", func() {
+		sch := generated.Schema()
+		st := store.New(sch, cache.Factory(store.New(sch, memory.Factory()), time.Minute))
+
+		world := generated.WorldFactory()
+		world.External().SetName("a")
+		_, err := st.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		_, err = st.Get(ctx, generated.WorldIdentity("b"))
+		Expect(errors.Is(err, constants.ErrNoSuchObject)).To(BeTrue())
+		ret, err := st.List(ctx, generated.WorldKindIdentity())
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(1))
+
+		update := func(tx store.Store, description string) error {
+			ret, err := tx.Get(ctx, generated.WorldIdentity("a"))
+			if err != nil {
+				return err
+			}
+
+			ret.(generated.World).External().SetDescription(description)
+			_, err = tx.Update(ctx, ret.Metadata().Identity(), ret)
+			return err
+		}
+
+		err = st.(store.Transactional).Txn(ctx, func(tx store.Store) error {
+			world := generated.WorldFactory()
+			world.External().SetName("b")
+			_, err := tx.Create(ctx, world)
+			if err != nil {
+				return err
+			}
+
+			return update(tx, "committed")
+		})
+		Expect(err).To(BeNil())
+
+		obj, err := st.Get(ctx, generated.WorldIdentity("a"))
+		Expect(err).To(BeNil())
+		Expect(obj.(generated.World).External().Description()).To(Equal("committed"))
+		_, err = st.Get(ctx, generated.WorldIdentity("b"))
+		Expect(err).To(BeNil())
+		ret, err = st.List(ctx, generated.WorldKindIdentity())
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(2))
+
+		err = st.(store.Transactional).Txn(ctx, func(tx store.Store) error {
+			err := update(tx, "rolled back")
+			if err != nil {
+				return err
+			}
+
+			return fmt.Errorf("rollback")
+		})
+		Expect(err).ToNot(BeNil())
+
+		obj, err = st.Get(ctx, generated.WorldIdentity("a"))
+		Expect(err).To(BeNil())
+		Expect(obj.(generated.World).External().Description()).To(Equal("committed"))
+	})
+})
@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"container/heap"
+	"encoding/json"
+	"time"
+
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/store"
+)
+
+// Eviction selects the entries dropped when the cache is full
+type Eviction int
+
+const (
+	// LRU evicts the least recently used entries
+	LRU Eviction = iota
+	// LFU evicts the least frequently used entries
+	LFU
+)
+
+// Limits bound the cache size, zero values are unlimited.
+// Bytes are approximated by the JSON size of the cached objects.
+// MaxStale caps the staleness StaleWhileRevalidate can ask for,
+// by default expired entries are never served
+type Limits struct {
+	MaxEntries int
+	MaxBytes   int
+	Eviction   Eviction
+	MaxStale   time.Duration
+}
+
+// Statistics are the cache counters,
+// Stale counts the hits served while revalidating
+type Statistics struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Stale     uint64
+	Entries   int
+	Bytes     int
+	// Policies counts the objects cached with their own expiration
+	Policies int
+}
+
+// StatsReporter is implemented by the cache store
+type StatsReporter interface {
+	Stats() Statistics
+}
+
+// Stats returns the counters of a cache store
+func Stats(st store.Store) (Statistics, error) {
+	reporter, ok := st.(StatsReporter)
+	if !ok {
+		return Statistics{}, constants.ErrNotSupported
+	}
+
+	return reporter.Stats(), nil
+}
+
+// sweepMinimum is the number of entries sweeps start at
+const sweepMinimum = 64
+
+// _Usage tracks the eviction order of a cached object or list
+type _Usage struct {
+	Key   string
+	List  bool
+	Size  int
+	Uses  uint64
+	Tick  uint64
+	Index int
+}
+
+// _Usages is a heap with the next entry to evict on top
+type _Usages struct {
+	Items    []*_Usage
+	Eviction Eviction
+}
+
+func (h _Usages) Len() int {
+	return len(h.Items)
+}
+
+func (h _Usages) Less(i, j int) bool {
+	a, b := h.Items[i], h.Items[j]
+	if h.Eviction == LFU && a.Uses != b.Uses {
+		return a.Uses < b.Uses
+	}
+
+	return a.Tick < b.Tick
+}
+
+func (h _Usages) Swap(i, j int) {
+	h.Items[i], h.Items[j] = h.Items[j], h.Items[i]
+	h.Items[i].Index = i
+	h.Items[j].Index = j
+}
+
+func (h *_Usages) Push(x interface{}) {
+	u := x.(*_Usage)
+	u.Index = len(h.Items)
+	h.Items = append(h.Items, u)
+}
+
+func (h *_Usages) Pop() interface{} {
+	n := len(h.Items)
+	u := h.Items[n-1]
+	h.Items[n-1] = nil
+	h.Items = h.Items[:n-1]
+	u.Index = -1
+
+	return u
+}
+
+// track starts accounting a new entry, evicting others first
+// so that the limits hold, callers must hold the lock
+func (d *cachedStore) track(u *_Usage) {
+	oversized := d.Limits.MaxBytes > 0 && u.Size > d.Limits.MaxBytes
+	for !oversized && d.Usages.Len() > 0 && d.full(u.Size) {
+		d.evict(heap.Pop(&d.Usages).(*_Usage))
+	}
+
+	// sweep whenever the entries double, so unbounded
+	// caches do not keep the expired ones
+	if d.Usages.Len() >= d.NextSweep {
+		d.sweep()
+	}
+
+	d.Tick++
+	u.Tick = d.Tick
+	heap.Push(&d.Usages, u)
+	d.Bytes += u.Size
+
+	// entries larger than the limit are not kept
+	if oversized {
+		heap.Remove(&d.Usages, u.Index)
+		d.evict(u)
+	}
+}
+
+// full tells if adding an entry of the size exceeds the limits
+func (d *cachedStore) full(size int) bool {
+	if d.Limits.MaxEntries > 0 && d.Usages.Len() >= d.Limits.MaxEntries {
+		return true
+	}
+
+	return d.Limits.MaxBytes > 0 && d.Bytes+size > d.Limits.MaxBytes
+}
+
+// touch records a cache hit, callers must hold the lock
+func (d *cachedStore) touch(u *_Usage) {
+	d.Tick++
+	u.Tick = d.Tick
+	u.Uses++
+	if u.Index >= 0 {
+		heap.Fix(&d.Usages, u.Index)
+	}
+}
+
+// untrack stops accounting a dropped entry, callers must hold the lock
+func (d *cachedStore) untrack(u *_Usage) {
+	if u.Index >= 0 {
+		heap.Remove(&d.Usages, u.Index)
+	}
+	d.Bytes -= u.Size
+}
+
+// sweep drops the entries expired for longer than the
+// max staleness, callers must hold the lock
+func (d *cachedStore) sweep() {
+	now := time.Now()
+	expired := []*_Usage{}
+	for _, u := range d.Usages.Items {
+		var expires time.Time
+		if u.List {
+			expires = d.Lists[u.Key].Expires
+		} else {
+			expires = d.Entries[u.Key].Expires
+		}
+
+		if now.After(expires.Add(d.Limits.MaxStale)) {
+			expired = append(expired, u)
+		}
+	}
+
+	for _, u := range expired {
+		d.drop(u)
+	}
+
+	d.NextSweep = 2 * d.Usages.Len()
+	if d.NextSweep < sweepMinimum {
+		d.NextSweep = sweepMinimum
+	}
+}
+
+// evict drops the entry to make room, callers must hold the lock
+func (d *cachedStore) evict(u *_Usage) {
+	d.Statistics.Evictions++
+	d.drop(u)
+}
+
+// drop removes the entry, callers must hold the lock
+func (d *cachedStore) drop(u *_Usage) {
+	if u.List {
+		d.dropList(u.Key)
+		return
+	}
+
+	d.invalidate(store.ObjectIdentity(u.Key))
+}
+
+func (d *cachedStore) Stats() Statistics {
+	d.Lock.Lock()
+	defer d.Lock.Unlock()
+
+	res := d.Statistics
+	res.Entries = d.Usages.Len()
+	res.Bytes = d.Bytes
+	res.Policies = len(d.Policies)
+
+	return res
+}
+
+// sizeOf approximates the memory held by the objects
+func sizeOf(objs ...store.Object) int {
+	res := 0
+	for _, o := range objs {
+		data, err := json.Marshal(o)
+		if err == nil {
+			res += len(data)
+		}
+	}
+
+	return res
+}
@@ -16,6 +16,7 @@ type expiryOption interface {
 	// options.GetOption
 	options.CreateOption
 	options.UpdateOption
+	options.ListOption
 }
 
 func Expire(duration time.Duration) expiryOption {
@@ -46,6 +47,9 @@ func (d *cacheExpiryOption) GetCreateOption() options.Option {
 func (d *cacheExpiryOption) GetUpdateOption() options.Option {
 	return d
 }
+func (d *cacheExpiryOption) GetListOption() options.Option {
+	return d
+}
 
 // func (d *cacheExpiryOption) GetGetOption() options.Option {
 // 	return d
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wazofski/gostorz/store/options"
+)
+
+type cacheRevalidateOption struct {
+	Function options.OptionFunction
+}
+
+type revalidateOption interface {
+	options.Option
+	options.GetOption
+	options.ListOption
+}
+
+// StaleWhileRevalidate serves expired entries for up to maxStale,
+// capped at the MaxStale limit of the cache, while refreshing them
+// in the background. Entries expired for longer block until reloaded.
+func StaleWhileRevalidate(maxStale time.Duration) revalidateOption {
+	return &cacheRevalidateOption{
+		Function: func(options options.OptionHolder) error {
+			cacheOpts, ok := options.(*cacheOptions)
+			if !ok {
+				return nil
+			}
+
+			if maxStale < 0 {
+				return fmt.Errorf("invalid staleness [%d]", maxStale)
+			}
+
+			cacheOpts.MaxStale = maxStale
+			return nil
+		},
+	}
+}
+
+func (d *cacheRevalidateOption) ApplyFunction() options.OptionFunction {
+	return d.Function
+}
+func (d *cacheRevalidateOption) GetGetOption() options.Option {
+	return d
+}
+func (d *cacheRevalidateOption) GetListOption() options.Option {
+	return d
+}
@@ -2,11 +2,18 @@ package cache
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"golang.org/x/sync/singleflight"
+
+	"github.com/wazofski/gostorz/internal/constants"
 	"github.com/wazofski/gostorz/internal/logger"
-	"github.com/wazofski/gostorz/memory"
 	"github.com/wazofski/gostorz/store"
 	"github.com/wazofski/gostorz/store/options"
 )
@@ -16,15 +23,62 @@ var log = logger.Factory("cache")
 type cachedStore struct {
 	Schema            store.SchemaHolder
 	Store             store.Store
-	Cache             store.Store
 	DefaultExpiration time.Duration
-	Policies          map[store.ObjectIdentity]time.Duration
-	Modiffies         map[store.ObjectIdentity]time.Time
+	Policies          map[string]time.Duration
+	Entries           map[string]*_Entry
+	Lists             map[string]*_ListEntry
+	ListGeneration    uint64
+	ObjectGeneration  uint64
+	NextSweep         int
+	Limits            Limits
+	Usages            _Usages
+	Bytes             int
+	Tick              uint64
+	Statistics        Statistics
+	Loader            singleflight.Group
+	Lock              sync.Mutex
+}
+
+// _Entry is a cached object, or a cached miss when Object is nil.
+// Objects are indexed by both their id and primary key paths.
+type _Entry struct {
+	Object     store.Object
+	Paths      []string
+	Expires    time.Time
+	Usage      *_Usage
+	Refreshing bool
+}
+
+// _ListEntry is a cached List result of a kind
+type _ListEntry struct {
+	Kind       string
+	Objects    store.ObjectList
+	Expires    time.Time
+	Usage      *_Usage
+	Refreshing bool
+}
+
+// Invalidator is implemented by the cache store
+type Invalidator interface {
+	Invalidate(store.ObjectIdentity)
+}
+
+// Invalidate drops the cached entry of the identity and the cached
+// lists of its kind so the next read loads them from the backing store
+func Invalidate(st store.Store, identity store.ObjectIdentity) error {
+	invalidator, ok := st.(Invalidator)
+	if !ok {
+		return constants.ErrNotSupported
+	}
+
+	invalidator.Invalidate(identity)
+	return nil
 }
 
 type cacheOptions struct {
 	options.CommonOptionHolder
 	Expiration time.Duration
+	MaxStale   time.Duration
 }
 
 func newCacheOptions(d *cachedStore) cacheOptions {
@@ -41,17 +95,28 @@ func (d *cacheOptions) CommonOptions() *options.CommonOptionHolder {
 }
 
 func Factory(st store.Store, exp ...time.Duration) store.Factory {
+	return BoundedFactory(st, Limits{}, exp...)
+}
+
+// BoundedFactory makes a cache evicting entries beyond the limits
+func BoundedFactory(st store.Store, limits Limits, exp ...time.Duration) store.Factory {
 	if len(exp) > 1 {
 		log.Fatal(fmt.Errorf("multiple expiration durations cannot be set"))
 	}
 
+	if limits.MaxEntries < 0 || limits.MaxBytes < 0 || limits.MaxStale < 0 {
+		log.Fatal(fmt.Errorf("invalid cache limits %v", limits))
+	}
+
 	return func(schema store.SchemaHolder) (store.Store, error) {
 		client := &cachedStore{
-			Schema:    schema,
-			Store:     st,
-			Cache:     store.New(schema, memory.Factory()),
-			Policies:  make(map[store.ObjectIdentity]time.Duration),
-			Modiffies: make(map[store.ObjectIdentity]time.Time),
+			Schema:   schema,
+			Store:    st,
+			Policies: make(map[string]time.Duration),
+			Entries:  make(map[string]*_Entry),
+			Lists:    make(map[string]*_ListEntry),
+			Limits:   limits,
+			Usages:   _Usages{Eviction: limits.Eviction},
 		}
 
 		if len(exp) > 0 {
@@ -75,13 +140,19 @@ func (d *cachedStore) Create(
 		}
 	}
 
-	ret, err := d.Cache.Create(ctx, obj, opt...)
-	if copt.Expiration > 0 && err == nil {
-		d.Policies[ret.Metadata().Identity()] = copt.Expiration
-		d.Modiffies[ret.Metadata().Identity()] = time.Now()
+	ret, err := d.Store.Create(ctx, obj, opt...)
+	if err != nil {
+		return nil, err
 	}
 
-	return d.Store.Create(ctx, obj, opt...)
+	d.Lock.Lock()
+	defer d.Lock.Unlock()
+
+	d.ObjectGeneration++
+	d.put(ret, copt.Expiration)
+	d.invalidateLists(ret.Metadata().Kind())
+
+	return ret, nil
 }
 
 func (d *cachedStore) Update(
@@ -98,14 +169,58 @@ func (d *cachedStore) Update(
 		}
 	}
 
-	ret, err := d.Cache.Update(ctx, identity, obj, opt...)
-	if copt.Expiration > 0 && err == nil {
-		log.Printf("setting update expiration: %d", copt.Expiration)
-		d.Policies[ret.Metadata().Identity()] = copt.Expiration
-		d.Modiffies[ret.Metadata().Identity()] = time.Now()
+	ret, err := d.Store.Update(ctx, identity, obj, opt...)
+
+	d.Lock.Lock()
+	defer d.Lock.Unlock()
+
+	d.ObjectGeneration++
+	d.invalidateLists(d.kindOf(identity, obj))
+	d.invalidate(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	d.put(ret, copt.Expiration)
+
+	return ret, nil
+}
+
+// Patch forwards the patch and caches the patched object
+func (d *cachedStore) Patch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	patch store.Patch,
+	opt ...options.UpdateOption) (store.Object, error) {
+
+	copt := newCacheOptions(d)
+	for _, o := range opt {
+		err := o.ApplyFunction()(&copt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	patcher, ok := d.Store.(store.Patcher)
+	if !ok {
+		return nil, constants.ErrNotSupported
+	}
+
+	ret, err := patcher.Patch(ctx, identity, patch, opt...)
+
+	d.Lock.Lock()
+	defer d.Lock.Unlock()
+
+	d.ObjectGeneration++
+	d.invalidateLists(d.kindOf(identity, ret))
+	d.invalidate(identity)
+	if err != nil {
+		return nil, err
 	}
 
-	return d.Store.Update(ctx, identity, obj, opt...)
+	d.put(ret, copt.Expiration)
+
+	return ret, nil
 }
 
 func (d *cachedStore) Delete(
@@ -113,13 +228,16 @@ func (d *cachedStore) Delete(
 	identity store.ObjectIdentity,
 	opt ...options.DeleteOption) error {
 
-	err := d.Cache.Delete(ctx, identity, opt...)
-	if err == nil {
-		delete(d.Policies, identity)
-		delete(d.Modiffies, identity)
-	}
+	err := d.Store.Delete(ctx, identity, opt...)
+
+	d.Lock.Lock()
+	defer d.Lock.Unlock()
+
+	d.ObjectGeneration++
+	d.invalidateLists(d.kindOf(identity, nil))
+	d.invalidate(identity)
 
-	return d.Store.Delete(ctx, identity, opt...)
+	return err
 }
 
 func (d *cachedStore) Get(
@@ -127,49 +245,492 @@ func (d *cachedStore) Get(
 	identity store.ObjectIdentity,
 	opt ...options.GetOption) (store.Object, error) {
 
-	existing, err := d.Store.Get(ctx, identity, opt...)
+	copt := newCacheOptions(d)
+	for _, o := range opt {
+		err := o.ApplyFunction()(&copt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the cache holds whole objects, projections are read through
+	if len(copt.Fields) > 0 {
+		return d.Store.Get(ctx, identity, opt...)
+	}
+
+	path := identity.Path()
+	entry, stale, generation := d.lookup(path, copt.MaxStale)
+	load := func() (interface{}, error) {
+		return d.load(_Detached{ctx}, entry, generation, identity, opt...)
+	}
+
+	if stale {
+		go d.Loader.Do(path, load)
+	}
+
+	if entry != nil {
+		if entry.Object == nil {
+			return nil, constants.ErrNoSuchObject
+		}
+		return entry.Object.Clone(), nil
+	}
 
-	if existing == nil || err != nil {
+	// concurrent misses share a single backing store call
+	ret, err := wait(ctx, d.Loader.DoChan(path, load))
+	if err != nil {
 		return nil, err
 	}
 
-	cached, cached_err := d.Cache.Get(ctx, identity)
+	return ret.(store.Object).Clone(), nil
+}
+
+// load reads the object from the backing store into the cache,
+// unless a write raced it. Refreshes of a stale entry are raced when
+// the entry was dropped or replaced since, loads of a miss when any
+// object was written since the generation was read
+func (d *cachedStore) load(
+	ctx context.Context,
+	entry *_Entry,
+	generation uint64,
+	identity store.ObjectIdentity,
+	opt ...options.GetOption) (store.Object, error) {
+
+	path := identity.Path()
+	existing, err := d.Store.Get(ctx, identity, opt...)
+
+	d.Lock.Lock()
+	defer d.Lock.Unlock()
+
+	raced := generation != d.ObjectGeneration
+	if entry != nil {
+		raced = d.Entries[path] != entry
+		entry.Refreshing = false
+	}
+
+	if raced {
+		return existing, err
+	}
+
+	if err != nil {
+		d.invalidate(identity)
+		if errors.Is(err, constants.ErrNoSuchObject) {
+			d.putMissing(path)
+		}
+		return nil, err
+	}
 
-	has_expired := false
-	exp := time.Duration(0)
-	exp, ok := d.Policies[existing.Metadata().Identity()]
+	exp, ok := d.Policies[existing.Metadata().Identity().Path()]
 	if !ok {
 		exp = d.DefaultExpiration
 	}
+	d.put(existing, exp)
+
+	return existing, nil
+}
+
+func (d *cachedStore) List(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.ListOption) (store.ObjectList, error) {
 
-	if exp > 0 {
-		expt := d.Modiffies[existing.Metadata().Identity()].Add(exp)
-		if expt.Before(time.Now()) {
-			// log.Printf("has expired %d %s < %s", exp, expt.String(), time.Now().String())
-			has_expired = true
+	copt := newCacheOptions(d)
+	for _, o := range opt {
+		err := o.ApplyFunction()(&copt)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	if has_expired || cached == nil {
-		if existing != nil && err == nil {
-			d.Policies[existing.Metadata().Identity()] = exp
-			d.Modiffies[existing.Metadata().Identity()] = time.Now()
-			if cached == nil {
-				d.Cache.Create(ctx, existing)
-			} else {
-				d.Cache.Update(ctx, identity, existing)
-			}
-		}
-		return existing, err
+	key, err := listKey(identity, copt.CommonOptionHolder)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, stale, generation := d.lookupList(key, copt.MaxStale)
+	load := func() (interface{}, error) {
+		return d.loadList(_Detached{ctx}, key, generation, copt, identity, opt...)
+	}
+
+	if stale {
+		go d.Loader.Do("list "+key, load)
+	}
+
+	if entry != nil {
+		return cloneList(entry.Objects), nil
+	}
+
+	ret, err := wait(ctx, d.Loader.DoChan("list "+key, load))
+	if err != nil {
+		return nil, err
 	}
 
-	return cached, cached_err
+	return cloneList(ret.(store.ObjectList)), nil
 }
 
-func (d *cachedStore) List(
+// loadList reads the list from the backing store into the cache
+func (d *cachedStore) loadList(
 	ctx context.Context,
+	key string,
+	generation uint64,
+	copt cacheOptions,
 	identity store.ObjectIdentity,
 	opt ...options.ListOption) (store.ObjectList, error) {
 
-	return d.Store.List(ctx, identity, opt...)
+	res, err := d.Store.List(ctx, identity, opt...)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Lock.Lock()
+	defer d.Lock.Unlock()
+
+	// skip caching results raced by a write of the kind
+	d.dropList(key)
+	if copt.Expiration > 0 && generation == d.ListGeneration {
+		entry := &_ListEntry{
+			Kind:    identity.Type(),
+			Objects: cloneList(res),
+			Expires: time.Now().Add(copt.Expiration),
+			Usage: &_Usage{
+				Key:  key,
+				List: true,
+				Size: sizeOf(res...),
+			},
+		}
+		d.Lists[key] = entry
+		d.track(entry.Usage)
+	}
+
+	return res, nil
+}
+
+// ListPage forwards to the backing store, pages are not cached
+// as their tokens and totals go stale with any write of the kind
+func (d *cachedStore) ListPage(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.ListOption) (*store.Page, error) {
+
+	pager, ok := d.Store.(store.Pager)
+	if !ok {
+		return nil, constants.ErrNotSupported
+	}
+
+	return pager.ListPage(ctx, identity, opt...)
+}
+
+// Count forwards to the backing store, counts are not cached
+func (d *cachedStore) Count(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.ListOption) (int, error) {
+
+	return store.Count(ctx, d.Store, identity, opt...)
+}
+
+// Aggregate forwards to the backing store, results are not cached
+func (d *cachedStore) Aggregate(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	agg store.Aggregation,
+	opt ...options.ListOption) ([]store.AggregateResult, error) {
+
+	return store.AggregateList(ctx, d.Store, identity, agg, opt...)
+}
+
+func (d *cachedStore) Invalidate(identity store.ObjectIdentity) {
+	log.Printf("invalidate %s", identity.Path())
+
+	d.Lock.Lock()
+	defer d.Lock.Unlock()
+
+	d.ObjectGeneration++
+	d.invalidateLists(d.kindOf(identity, nil))
+	d.invalidate(identity)
+}
+
+func (d *cachedStore) Watch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.WatchOption) (<-chan store.Event, error) {
+
+	watcher, ok := d.Store.(store.Watcher)
+	if !ok {
+		return nil, constants.ErrNotSupported
+	}
+
+	return watcher.Watch(ctx, identity, opt...)
+}
+
+// put caches the object under its id and primary key paths,
+// callers must hold the lock
+func (d *cachedStore) put(obj store.Object, exp time.Duration) {
+	paths := []string{
+		obj.Metadata().Identity().Path(),
+		store.ObjectIdentity(
+			fmt.Sprintf("%s/%s", obj.Metadata().Kind(), obj.PrimaryKey())).Path(),
+	}
+
+	for _, p := range paths {
+		d.invalidate(store.ObjectIdentity(p))
+	}
+
+	if exp <= 0 {
+		return
+	}
+
+	entry := &_Entry{
+		Object:  obj.Clone(),
+		Paths:   paths,
+		Expires: time.Now().Add(exp),
+		Usage: &_Usage{
+			Key:  paths[0],
+			Size: sizeOf(obj),
+		},
+	}
+
+	for _, p := range paths {
+		d.Entries[p] = entry
+	}
+	d.track(entry.Usage)
+
+	// only expirations other than the default are kept
+	if exp != d.DefaultExpiration {
+		d.Policies[paths[0]] = exp
+	}
+}
+
+// putMissing caches a not found result, callers must hold the lock
+func (d *cachedStore) putMissing(path string) {
+	if d.DefaultExpiration <= 0 {
+		return
+	}
+
+	entry := &_Entry{
+		Paths:   []string{path},
+		Expires: time.Now().Add(d.DefaultExpiration),
+		Usage: &_Usage{
+			Key:  path,
+			Size: len(path),
+		},
+	}
+
+	d.Entries[path] = entry
+	d.track(entry.Usage)
+}
+
+// invalidate drops the entry along with all its paths
+// and its expiration policy, callers must hold the lock
+func (d *cachedStore) invalidate(identity store.ObjectIdentity) {
+	delete(d.Policies, identity.Path())
+
+	entry := d.Entries[identity.Path()]
+	if entry == nil {
+		return
+	}
+
+	if entry.Object != nil {
+		delete(d.Policies, entry.Object.Metadata().Identity().Path())
+	}
+
+	for _, p := range entry.Paths {
+		delete(d.Entries, p)
+	}
+	d.untrack(entry.Usage)
+}
+
+// dropList drops the cached list, callers must hold the lock
+func (d *cachedStore) dropList(key string) {
+	entry := d.Lists[key]
+	if entry == nil {
+		return
+	}
+
+	delete(d.Lists, key)
+	d.untrack(entry.Usage)
+}
+
+// lookup returns the entry of the path when fresh or expired for
+// less than maxStale, telling if it is stale and not yet being
+// refreshed, along with the current object generation.
+// Counts hits and misses
+func (d *cachedStore) lookup(path string, maxStale time.Duration) (*_Entry, bool, uint64) {
+	d.Lock.Lock()
+	defer d.Lock.Unlock()
+
+	entry := d.Entries[path]
+	if entry == nil {
+		d.Statistics.Misses++
+		return nil, false, d.ObjectGeneration
+	}
+
+	stale, ok := d.usable(entry.Expires, maxStale)
+	if !ok {
+		return nil, false, d.ObjectGeneration
+	}
+	d.touch(entry.Usage)
+
+	stale = stale && !entry.Refreshing
+	entry.Refreshing = entry.Refreshing || stale
+
+	return entry, stale, d.ObjectGeneration
+}
+
+// lookupList is the lookup of lists, also returning the
+// current list generation
+func (d *cachedStore) lookupList(key string, maxStale time.Duration) (*_ListEntry, bool, uint64) {
+	d.Lock.Lock()
+	defer d.Lock.Unlock()
+
+	entry := d.Lists[key]
+	if entry == nil {
+		d.Statistics.Misses++
+		return nil, false, d.ListGeneration
+	}
+
+	stale, ok := d.usable(entry.Expires, maxStale)
+	if !ok {
+		return nil, false, d.ListGeneration
+	}
+	d.touch(entry.Usage)
+
+	stale = stale && !entry.Refreshing
+	entry.Refreshing = entry.Refreshing || stale
+
+	return entry, stale, d.ListGeneration
+}
+
+// usable tells if an entry expiring at the time can be served
+// and if it is stale, the staleness asked for is capped at the
+// limit of the cache. Callers must hold the lock
+func (d *cachedStore) usable(expires time.Time, maxStale time.Duration) (bool, bool) {
+	if maxStale > d.Limits.MaxStale {
+		maxStale = d.Limits.MaxStale
+	}
+
+	now := time.Now()
+	if now.Before(expires) {
+		d.Statistics.Hits++
+		return false, true
+	}
+
+	if now.Before(expires.Add(maxStale)) {
+		d.Statistics.Hits++
+		d.Statistics.Stale++
+		return true, true
+	}
+
+	d.Statistics.Misses++
+	return false, false
+}
+
+// invalidateLists drops the cached lists of the kind,
+// or every cached list when the kind is unknown.
+// Callers must hold the lock
+func (d *cachedStore) invalidateLists(kind string) {
+	d.ListGeneration++
+
+	kind = strings.ToLower(kind)
+	for k, l := range d.Lists {
+		if len(kind) == 0 || l.Kind == kind {
+			d.dropList(k)
+		}
+	}
+}
+
+// kindOf resolves the kind of the identity, callers must hold the lock
+func (d *cachedStore) kindOf(identity store.ObjectIdentity, obj store.Object) string {
+	if identity.Type() != "id" {
+		return identity.Type()
+	}
+
+	if obj != nil {
+		return obj.Metadata().Kind()
+	}
+
+	entry := d.Entries[identity.Path()]
+	if entry != nil && entry.Object != nil {
+		return entry.Object.Metadata().Kind()
+	}
+
+	return ""
+}
+
+// listKey normalizes the identity and list options into a cache key
+func listKey(identity store.ObjectIdentity, copt options.CommonOptionHolder) (string, error) {
+	key := struct {
+		Type       string                     `json:"type"`
+		KeyFilter  []string                   `json:"kf,omitempty"`
+		PropFilter *options.PropFilterSetting `json:"pf,omitempty"`
+		Filter     interface{}                `json:"filter,omitempty"`
+		OrderBy    string                     `json:"orderBy,omitempty"`
+		Inc        bool                       `json:"inc"`
+		PageSize   int                        `json:"pageSize,omitempty"`
+		PageOffset int                        `json:"pageOffset,omitempty"`
+		Continue   string                     `json:"continue,omitempty"`
+		Fields     []string                   `json:"fields,omitempty"`
+	}{
+		Type:       identity.Type(),
+		PropFilter: copt.PropFilter,
+		OrderBy:    copt.OrderBy,
+		Inc:        copt.OrderIncremental,
+		PageSize:   copt.PageSize,
+		PageOffset: copt.PageOffset,
+		Continue:   copt.Continue,
+		Fields:     copt.Fields,
+	}
+
+	if copt.KeyFilter != nil {
+		key.KeyFilter = append([]string{}, *copt.KeyFilter...)
+		sort.Strings(key.KeyFilter)
+	}
+
+	if copt.Filter != nil {
+		key.Filter = copt.Filter
+	}
+
+	data, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// wait returns the result of a shared load, or the error of the
+// context when it ends first, leaving the load to the other callers
+func wait(ctx context.Context, ch <-chan singleflight.Result) (interface{}, error) {
+	select {
+	case res := <-ch:
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// _Detached keeps the values of the context of the caller starting
+// a shared load, without its cancellation or deadline
+type _Detached struct {
+	context.Context
+}
+
+func (_Detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (_Detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (_Detached) Err() error {
+	return nil
+}
+
+func cloneList(list store.ObjectList) store.ObjectList {
+	res := store.ObjectList{}
+	for _, o := range list {
+		res = append(res, o.Clone())
+	}
+
+	return res
 }
@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/store"
+	"github.com/wazofski/gostorz/store/options"
+)
+
+// Txn runs the function in a transaction of the backing store.
+// Reads and writes of the transaction bypass the cache, the objects
+// written are invalidated along with the lists of their kinds
+// once the transaction ends
+func (d *cachedStore) Txn(
+	ctx context.Context,
+	fn func(store.Store) error) error {
+
+	transactional, ok := d.Store.(store.Transactional)
+	if !ok {
+		return constants.ErrNotSupported
+	}
+
+	written := &_Written{}
+	err := transactional.Txn(ctx, func(tx store.Store) error {
+		return fn(&_TxnStore{Store: tx, Written: written})
+	})
+
+	d.Lock.Lock()
+	defer d.Lock.Unlock()
+
+	d.ObjectGeneration++
+	for i, identity := range written.Identities {
+		d.invalidateLists(d.kindOf(identity, written.Objects[i]))
+		d.invalidate(identity)
+	}
+
+	return err
+}
+
+// _Written are the identities written through a transaction
+// along with the objects written, when known
+type _Written struct {
+	Identities []store.ObjectIdentity
+	Objects    []store.Object
+}
+
+func (w *_Written) add(identity store.ObjectIdentity, obj store.Object) {
+	w.Identities = append(w.Identities, identity)
+	w.Objects = append(w.Objects, obj)
+	if obj == nil {
+		return
+	}
+
+	w.Identities = append(w.Identities,
+		obj.Metadata().Identity(),
+		store.ObjectIdentity(
+			fmt.Sprintf("%s/%s", obj.Metadata().Kind(), obj.PrimaryKey())))
+	w.Objects = append(w.Objects, obj, obj)
+}
+
+// _TxnStore records the writes of a transaction
+type _TxnStore struct {
+	store.Store
+	Written *_Written
+}
+
+func (t *_TxnStore) Create(
+	ctx context.Context,
+	obj store.Object,
+	opt ...options.CreateOption) (store.Object, error) {
+
+	ret, err := t.Store.Create(ctx, obj, opt...)
+	if ret != nil {
+		t.Written.add(ret.Metadata().Identity(), ret)
+	}
+
+	return ret, err
+}
+
+func (t *_TxnStore) Update(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	obj store.Object,
+	opt ...options.UpdateOption) (store.Object, error) {
+
+	ret, err := t.Store.Update(ctx, identity, obj, opt...)
+	t.Written.add(identity, ret)
+
+	return ret, err
+}
+
+func (t *_TxnStore) Patch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	patch store.Patch,
+	opt ...options.UpdateOption) (store.Object, error) {
+
+	patcher, ok := t.Store.(store.Patcher)
+	if !ok {
+		return nil, constants.ErrNotSupported
+	}
+
+	ret, err := patcher.Patch(ctx, identity, patch, opt...)
+	t.Written.add(identity, ret)
+
+	return ret, err
+}
+
+func (t *_TxnStore) Delete(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.DeleteOption) error {
+
+	t.Written.add(identity, nil)
+
+	return t.Store.Delete(ctx, identity, opt...)
+}
+
+// Txn nests the transaction, recording its writes along
+func (t *_TxnStore) Txn(
+	ctx context.Context,
+	fn func(store.Store) error) error {
+
+	transactional, ok := t.Store.(store.Transactional)
+	if !ok {
+		return constants.ErrNotSupported
+	}
+
+	return transactional.Txn(ctx, func(tx store.Store) error {
+		return fn(&_TxnStore{Store: tx, Written: t.Written})
+	})
+}
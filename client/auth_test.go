@@ -0,0 +1,258 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/wazofski/gostorz/client"
+	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/memory"
+	"github.com/wazofski/gostorz/rest"
+	"github.com/wazofski/gostorz/store"
+	"github.com/wazofski/gostorz/store/options"
+)
+
+var _ = Describe("client auth", func() {
+
+	key := []byte("secret")
+	var stop context.CancelFunc
+	var backend *failingStore
+
+	BeforeEach(func() {
+		sch := generated.Schema()
+		backend = &failingStore{Store: store.New(sch, memory.Factory())}
+		srv := rest.Server(sch, backend,
+			rest.TypeMethods(generated.WorldKind(),
+				rest.ActionGet, rest.ActionCreate,
+				rest.ActionDelete, rest.ActionUpdate),
+			rest.Authenticate(rest.JWT(key)),
+			rest.Authorize(rest.RBAC(
+				rest.Grant{
+					Role:    "reader",
+					Actions: []rest.Action{rest.ActionGet},
+				},
+				rest.Grant{
+					Role:  "writer",
+					Kinds: []string{generated.WorldKind()},
+					Actions: []rest.Action{
+						rest.ActionGet, rest.ActionCreate,
+						rest.ActionUpdate, rest.ActionDelete,
+					},
+				})))
+
+		stop = srv.Listen(8003)
+		Eventually(func() error {
+			conn, err := net.Dial("tcp", "localhost:8003")
+			if err == nil {
+				conn.Close()
+			}
+			return err
+		}).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		stop()
+	})
+
+	token := func(claims map[string]interface{}) string {
+		tok, err := rest.SignJWT(key, claims)
+		Expect(err).To(BeNil())
+		return tok
+	}
+
+	connect := func(tok string) store.Store {
+		return store.New(generated.Schema(),
+			client.Factory("http://localhost:8003/",
+				client.Header("Authorization", "Bearer "+tok)))
+	}
+
+	It("can enforce roles", func() {
+		writer := connect(token(map[string]interface{}{
+			"sub":   "alice",
+			"roles": []string{"writer"},
+		}))
+		reader := connect(token(map[string]interface{}{
+			"sub":   "bob",
+			"roles": []string{"reader"},
+		}))
+
+		world := generated.WorldFactory()
+		world.External().SetName("secured")
+		_, err := writer.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		_, err = reader.Get(ctx, generated.WorldIdentity("secured"))
+		Expect(err).To(BeNil())
+
+		_, err = reader.List(ctx, generated.WorldIdentity(""))
+		Expect(err).To(BeNil())
+
+		err = reader.Delete(ctx, generated.WorldIdentity("secured"))
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("403"))
+
+		err = writer.Delete(ctx, generated.WorldIdentity("secured"))
+		Expect(err).To(BeNil())
+	})
+
+	It("cannot bypass authorization when lookups fail", func() {
+		writer := connect(token(map[string]interface{}{
+			"sub":   "alice",
+			"roles": []string{"writer"},
+		}))
+		reader := connect(token(map[string]interface{}{
+			"sub":   "bob",
+			"roles": []string{"reader"},
+		}))
+
+		world := generated.WorldFactory()
+		world.External().SetName("secured")
+		created, err := writer.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		err = reader.Delete(ctx, store.ObjectIdentity("id/missing"))
+		Expect(errors.Is(err, constants.ErrNoSuchObject)).To(BeTrue())
+
+		atomic.StoreInt32(&backend.Failing, 1)
+		err = reader.Delete(ctx, created.Metadata().Identity())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("500"))
+
+		_, err = reader.Update(ctx, created.Metadata().Identity(), created)
+		Expect(err).ToNot(BeNil())
+		atomic.StoreInt32(&backend.Failing, 0)
+
+		ret, err := writer.Get(ctx, generated.WorldIdentity("secured"))
+		Expect(err).To(BeNil())
+		Expect(ret.Metadata().Revision()).To(Equal(created.Metadata().Revision()))
+
+		Expect(writer.Delete(ctx, generated.WorldIdentity("secured"))).To(Succeed())
+	})
+
+	It("cannot probe ids", func() {
+		writer := connect(token(map[string]interface{}{
+			"sub":   "alice",
+			"roles": []string{"writer"},
+		}))
+		stranger := connect(token(map[string]interface{}{"sub": "eve"}))
+
+		world := generated.WorldFactory()
+		world.External().SetName("probed")
+		created, err := writer.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		second := generated.SecondWorldFactory()
+		second.External().SetName("hidden")
+		hidden, err := backend.Store.Create(ctx, second)
+		Expect(err).To(BeNil())
+
+		for _, id := range []store.ObjectIdentity{
+			store.ObjectIdentity("id/missing"),
+			created.Metadata().Identity(),
+		} {
+			_, err = stranger.Get(ctx, id)
+			Expect(errors.Is(err, constants.ErrNoSuchObject)).To(BeTrue(), id.Path())
+
+			err = stranger.Delete(ctx, id)
+			Expect(errors.Is(err, constants.ErrNoSuchObject)).To(BeTrue(), id.Path())
+		}
+
+		_, err = writer.Get(ctx, hidden.Metadata().Identity())
+		Expect(errors.Is(err, constants.ErrNoSuchObject)).To(BeTrue())
+
+		_, err = writer.Get(ctx, created.Metadata().Identity())
+		Expect(err).To(BeNil())
+		Expect(writer.Delete(ctx, created.Metadata().Identity())).To(Succeed())
+	})
+
+	It("can reject invalid tokens", func() {
+		forged, err := rest.SignJWT([]byte("other"), map[string]interface{}{
+			"roles": []string{"writer"},
+		})
+		Expect(err).To(BeNil())
+
+		expired := token(map[string]interface{}{
+			"roles": []string{"writer"},
+			"exp":   time.Now().Add(-time.Minute).Unix(),
+		})
+
+		for _, tok := range []string{forged, expired, "garbage"} {
+			_, err = connect(tok).Get(ctx, generated.WorldIdentity("secured"))
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("401"))
+		}
+	})
+
+	It("can report JSON errors", func() {
+		for code, tok := range map[int]string{
+			http.StatusUnauthorized: "",
+			http.StatusForbidden:    token(map[string]interface{}{"sub": "eve"}),
+		} {
+			expected := map[int]string{
+				http.StatusUnauthorized: constants.CodeUnauthorized,
+				http.StatusForbidden:    constants.CodeForbidden,
+			}[code]
+
+			req, err := http.NewRequest(http.MethodGet,
+				"http://localhost:8003/world", nil)
+			Expect(err).To(BeNil())
+			if len(tok) > 0 {
+				req.Header.Set("Authorization", "Bearer "+tok)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).To(BeNil())
+			defer resp.Body.Close()
+
+			Expect(resp.StatusCode).To(Equal(code))
+			Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+
+			body := rest.ErrorResponse{}
+			Expect(json.NewDecoder(resp.Body).Decode(&body)).To(Succeed())
+			Expect(body.Error.Code).To(Equal(expected), fmt.Sprint(code))
+		}
+	})
+
+	It("can authenticate API keys", func() {
+		authn := rest.APIKeys(map[string]rest.Principal{
+			"k1": {Subject: "service", Roles: []string{"reader"}},
+		})
+
+		req, _ := http.NewRequest(http.MethodGet, "http://localhost:8003/world", nil)
+		req.Header.Set("X-API-Key", "k1")
+		principal, err := authn.Authenticate(req)
+		Expect(err).To(BeNil())
+		Expect(principal.Subject).To(Equal("service"))
+
+		req.Header.Set("X-API-Key", "k2")
+		_, err = authn.Authenticate(req)
+		Expect(err).ToNot(BeNil())
+	})
+})
+
+// failingStore fails the Get calls while Failing is set
+type failingStore struct {
+	store.Store
+	Failing int32
+}
+
+func (d *failingStore) Get(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.GetOption) (store.Object, error) {
+
+	if atomic.LoadInt32(&d.Failing) != 0 {
+		return nil, errors.New("store unavailable")
+	}
+
+	return d.Store.Get(ctx, identity, opt...)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/wazofski/gostorz/internal/constants"
 	"github.com/wazofski/gostorz/internal/logger"
+	"github.com/wazofski/gostorz/react"
 	"github.com/wazofski/gostorz/rest"
 	"github.com/wazofski/gostorz/store"
 	"github.com/wazofski/gostorz/store/options"
@@ -27,9 +28,18 @@ type restStore struct {
 	Schema      store.SchemaHolder
 	MakeRequest requestMaker
 	Headers     []headerOption
+	Versions    *_Versions
 }
 
-type requestMaker func(path *url.URL, content []byte, method string, headers map[string]string) ([]byte, error)
+type requestMaker func(path *url.URL, content []byte, method string, headers map[string]string) (_Response, error)
+
+// _Response of a request, 304 Not Modified is not an error
+type _Response struct {
+	Status int
+	ETag   string
+	Header http.Header
+	Data   []byte
+}
 
 type restOptions struct {
 	options.CommonOptionHolder
@@ -65,6 +75,7 @@ func Factory(serviceUrl string, headers ...headerOption) store.Factory {
 			Schema:      schema,
 			MakeRequest: makeHttpRequest,
 			Headers:     headers,
+			Versions:    newVersions(versionsLimit, versionsBytes),
 		}
 
 		log.Printf("initialized: %s", serviceUrl)
@@ -72,12 +83,12 @@ func Factory(serviceUrl string, headers ...headerOption) store.Factory {
 	}
 }
 
-func makeHttpRequest(path *url.URL, content []byte, requestType string, headers map[string]string) ([]byte, error) {
+func makeHttpRequest(path *url.URL, content []byte, requestType string, headers map[string]string) (_Response, error) {
 	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 
 	req, err := http.NewRequest(requestType, path.String(), strings.NewReader(string(content)))
 	if err != nil {
-		return nil, err
+		return _Response{}, err
 	}
 
 	for k, v := range headers {
@@ -90,22 +101,33 @@ func makeHttpRequest(path *url.URL, content []byte, requestType string, headers
 	resp, err := http.DefaultClient.Do(req)
 
 	if err != nil {
-		return nil, err
+		return _Response{}, err
 	}
 
 	rd, err := utils.ReadStream(resp.Body)
 
 	defer resp.Body.Close()
 
+	res := _Response{
+		Status: resp.StatusCode,
+		ETag:   resp.Header.Get("ETag"),
+		Header: resp.Header,
+		Data:   rd,
+	}
+
 	if err != nil {
-		return rd, err
+		return res, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		return res, nil
 	}
 
 	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
-		return rd, fmt.Errorf("http %d", resp.StatusCode)
+		return res, fmt.Errorf("http %d", resp.StatusCode)
 	}
 
-	return rd, nil
+	return res, nil
 }
 
 func processRequest(
@@ -113,26 +135,24 @@ func processRequest(
 	requestUrl *url.URL,
 	content []byte,
 	method string,
-	headers map[string]string) ([]byte, error) {
+	headers map[string]string) (_Response, error) {
 
 	reqId := uuid.New().String()
 	requestUrl.Path = strings.ReplaceAll(requestUrl.Path, "//", "/")
 	origin := strings.ReplaceAll(requestUrl.String(), requestUrl.Path, "")
 	headers["Origin"] = strings.ReplaceAll(origin, requestUrl.RawQuery, "")
 	headers["X-Request-ID"] = reqId
-	headers["Content-Type"] = "application/json"
+	if len(headers["Content-Type"]) == 0 {
+		headers["Content-Type"] = "application/json"
+	}
 	headers["X-Requested-With"] = "XMLHttpRequest"
 
 	log.Printf("%s %s", strings.ToLower(method), requestUrl)
 	// log.Printf("X-Request-ID %s", reqId)
 
-	data, err := client.MakeRequest(requestUrl, content, method, headers)
-	cerr := errorCheck(data)
-	if err == nil {
-		err = cerr
-	} else if cerr != nil {
-		err = fmt.Errorf("%s %s", err, cerr)
-	}
+	res, err := client.MakeRequest(requestUrl, content, method, headers)
+	data := res.Data
+	err = responseError(err, data)
 
 	if err != nil {
 		// log.Println(err)
@@ -147,35 +167,61 @@ func processRequest(
 		if len(data) > 0 {
 			log.Printf("response content: %s", string(data))
 		}
-		return nil, err
+		return res, err
+	}
+
+	return res, err
+}
+
+// responseError turns the JSON error body back into
+// the sentinel errors of internal/constants
+func responseError(err error, response []byte) error {
+	cerr := errorCheck(response)
+	if cerr == nil {
+		return err
 	}
 
-	return data, err
+	if err == nil {
+		return cerr
+	}
+
+	return fmt.Errorf("%s: %w", err, cerr)
 }
 
 func errorCheck(response []byte) error {
-	str := string(response)
-	if len(str) == 0 {
+	if len(response) == 0 {
 		return nil
 	}
 
-	um := make(map[string]interface{})
+	res := rest.ErrorResponse{}
+	err := json.Unmarshal(response, &res)
+	if err != nil || len(res.Error.Code) == 0 {
+		return nil
+	}
 
-	err := json.Unmarshal(response, &um)
-	if err == nil {
-		if v, found := um["errors"]; found {
-			return errors.New(v.([]interface{})[0].(string))
+	if res.Error.Code == constants.CodeValidation {
+		return &store.ValidationError{
+			Path:   res.Error.Path,
+			Reason: res.Error.Message,
 		}
-		if v, found := um["error"]; found {
-			m := v.(map[string]interface{})
+	}
 
-			return fmt.Errorf("%v %s",
-				m["internal_code"],
-				m["internal"])
+	if res.Error.Code == constants.CodeAfterHook {
+		return &react.AfterHookError{
+			Err: errors.New(res.Error.Message),
 		}
 	}
 
-	return nil
+	sentinel := constants.ErrorForCode(res.Error.Code)
+	switch {
+	case sentinel == nil:
+		return errors.New(res.Error.Message)
+	case sentinel.Error() == res.Error.Message:
+		return sentinel
+	default:
+		return fmt.Errorf("%w: %s", sentinel,
+			strings.TrimPrefix(res.Error.Message, sentinel.Error()+": "))
+	}
 }
 
 func makePathForType(baseUrl *url.URL, obj store.Object) *url.URL {
@@ -234,6 +280,18 @@ func listParameters(ropt restOptions) string {
 		q.Add(rest.PageSizeArg, fmt.Sprintf("%d", opt.PageSize))
 	}
 
+	if len(opt.Continue) > 0 {
+		q.Add(rest.ContinueArg, opt.Continue)
+	}
+
+	if opt.TotalCount {
+		q.Add(rest.TotalArg, "true")
+	}
+
+	if len(opt.Fields) > 0 {
+		q.Add(rest.FieldsArg, strings.Join(opt.Fields, ","))
+	}
+
 	if opt.PropFilter != nil {
 		content, err := json.Marshal(opt.PropFilter)
 		if err != nil {
@@ -245,6 +303,17 @@ func listParameters(ropt restOptions) string {
 		}
 	}
 
+	if opt.Filter != nil {
+		content, err := json.Marshal(opt.Filter)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(content) > 0 {
+			q.Add(rest.FilterArg, string(content))
+		}
+	}
+
 	if opt.KeyFilter != nil {
 		content, err := json.Marshal(opt.KeyFilter)
 		if err != nil {
@@ -259,6 +328,17 @@ func listParameters(ropt restOptions) string {
 	return q.Encode()
 }
 
+func preconditionParameters(ropt restOptions) string {
+	opt := ropt.CommonOptions()
+
+	q := url.Values{}
+	if opt.Revision != nil {
+		q.Add(rest.RevisionArg, strconv.FormatInt(*opt.Revision, 10))
+	}
+
+	return q.Encode()
+}
+
 func (d *restStore) Create(
 	ctx context.Context,
 	obj store.Object,
@@ -284,7 +364,7 @@ func (d *restStore) Create(
 		return nil, err
 	}
 
-	data, err = processRequest(d,
+	res, err := processRequest(d,
 		makePathForType(d.BaseURL, obj),
 		data,
 		http.MethodPost,
@@ -295,13 +375,14 @@ func (d *restStore) Create(
 	}
 
 	clone := obj.Clone()
-	err = json.Unmarshal(data, &clone)
+	err = json.Unmarshal(res.Data, &clone)
 	if err != nil {
-		log.Printf(string(data))
-		clone = nil
+		log.Printf(string(res.Data))
+		return nil, err
 	}
 
-	return clone, err
+	d.Versions.Remember(clone.Metadata().Identity(), clone, res.ETag, res.Data)
+	return clone, nil
 }
 
 func (d *restStore) Update(
@@ -330,22 +411,71 @@ func (d *restStore) Update(
 		return nil, err
 	}
 
-	data, err = processRequest(d,
-		makePathForIdentity(d.BaseURL, identity, ""),
+	conditional(copt.Headers, "If-Match", d.Versions.Get(identity))
+
+	res, err := processRequest(d,
+		makePathForIdentity(d.BaseURL, identity, preconditionParameters(copt)),
 		data,
 		http.MethodPut,
 		copt.Headers)
 
+	if errors.Is(err, constants.ErrNoSuchObject) {
+		d.Versions.Forget(identity)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	clone := obj.Clone()
-	err = json.Unmarshal(data, &clone)
+	err = json.Unmarshal(res.Data, &clone)
+	if err == nil {
+		d.Versions.Remember(identity, clone, res.ETag, res.Data)
+	}
 
 	return clone, err
 }
 
+// Patch sends the patch as HTTP PATCH, the server applies it atomically
+func (d *restStore) Patch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	patch store.Patch,
+	opt ...options.UpdateOption) (store.Object, error) {
+
+	log.Printf("patch %s", identity.Path())
+
+	copt := newRestOptions(d)
+	var err error
+	for _, o := range opt {
+		err = o.ApplyFunction()(&copt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	copt.Headers["Content-Type"] = string(patch.Type)
+
+	res, err := processRequest(d,
+		makePathForIdentity(d.BaseURL, identity, preconditionParameters(copt)),
+		patch.Data,
+		http.MethodPatch,
+		copt.Headers)
+
+	if errors.Is(err, constants.ErrNoSuchObject) {
+		d.Versions.Forget(identity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := utils.UnmarshalObject(res.Data, d.Schema, utils.ObjeectKind(res.Data))
+	if err == nil {
+		d.Versions.Remember(identity, obj, res.ETag, res.Data)
+	}
+
+	return obj, err
+}
+
 func (d *restStore) Delete(
 	ctx context.Context,
 	identity store.ObjectIdentity,
@@ -362,12 +492,18 @@ func (d *restStore) Delete(
 		}
 	}
 
+	conditional(copt.Headers, "If-Match", d.Versions.Get(identity))
+
 	_, err = processRequest(d,
-		makePathForIdentity(d.BaseURL, identity, ""),
+		makePathForIdentity(d.BaseURL, identity, preconditionParameters(copt)),
 		[]byte{},
 		http.MethodDelete,
 		copt.Headers)
 
+	if err == nil || errors.Is(err, constants.ErrNoSuchObject) {
+		d.Versions.Forget(identity)
+	}
+
 	return err
 }
 
@@ -387,22 +523,50 @@ func (d *restStore) Get(
 		}
 	}
 
-	resp, err := processRequest(d,
-		makePathForIdentity(d.BaseURL, identity, ""),
+	// projections are neither conditional nor held as versions
+	params := ""
+	var version *_Version
+	if len(copt.Fields) > 0 {
+		q := url.Values{}
+		q.Add(rest.FieldsArg, strings.Join(copt.Fields, ","))
+		params = q.Encode()
+	} else {
+		version = d.Versions.Get(identity)
+		conditional(copt.Headers, "If-None-Match", version)
+	}
+
+	res, err := processRequest(d,
+		makePathForIdentity(d.BaseURL, identity, params),
 		[]byte{},
 		http.MethodGet,
 		copt.Headers)
 
+	if errors.Is(err, constants.ErrNoSuchObject) {
+		d.Versions.Forget(identity)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	resp := res.Data
+	if res.Status == http.StatusNotModified {
+		if version == nil {
+			return nil, fmt.Errorf("http %d: no version held", res.Status)
+		}
+		resp = version.Data
+	}
+
 	tp := identity.Type()
 	if tp == "id" {
 		tp = utils.ObjeectKind(resp)
 	}
 
-	return utils.UnmarshalObject(resp, d.Schema, tp)
+	obj, err := utils.UnmarshalObject(resp, d.Schema, tp)
+	if err == nil && res.Status != http.StatusNotModified && len(copt.Fields) == 0 {
+		d.Versions.Remember(identity, obj, res.ETag, resp)
+	}
+
+	return obj, err
 }
 
 func (d *restStore) List(
@@ -410,6 +574,20 @@ func (d *restStore) List(
 	identity store.ObjectIdentity,
 	opt ...options.ListOption) (store.ObjectList, error) {
 
+	page, err := d.ListPage(ctx, identity, opt...)
+	if err != nil {
+		return nil, err
+	}
+
+	return page.Items, nil
+}
+
+// ListPage reads the continue token and the total count of the reply headers
+func (d *restStore) ListPage(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.ListOption) (*store.Page, error) {
+
 	log.Printf("list %s", identity)
 
 	var err error
@@ -434,15 +612,28 @@ func (d *restStore) List(
 		return nil, err
 	}
 
+	page := &store.Page{
+		Items:    store.ObjectList{},
+		Continue: res.Header.Get(rest.ContinueHeader),
+	}
+
+	total := res.Header.Get(rest.TotalHeader)
+	if len(total) > 0 {
+		count, err := strconv.Atoi(total)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &count
+	}
+
 	parsed := []*json.RawMessage{}
-	err = json.Unmarshal(res, &parsed)
+	err = json.Unmarshal(res.Data, &parsed)
 	if err != nil {
 		return nil, err
 	}
 
-	marshalledResult := store.ObjectList{}
 	if len(parsed) == 0 {
-		return marshalledResult, nil
+		return page, nil
 	}
 
 	resource := d.Schema.ObjectForKind(utils.ObjeectKind(*parsed[0]))
@@ -451,10 +642,83 @@ func (d *restStore) List(
 		clone := resource.Clone()
 		clone.UnmarshalJSON(toBytes(r))
 
-		marshalledResult = append(marshalledResult, clone)
+		page.Items = append(page.Items, clone)
+	}
+
+	return page, nil
+}
+
+// Count sends a count aggregation
+func (d *restStore) Count(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.ListOption) (int, error) {
+
+	ret, err := d.Aggregate(ctx, identity,
+		store.Aggregation{Function: store.AggregateCount}, opt...)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(ret) != 1 {
+		return 0, fmt.Errorf("unexpected count of %d groups", len(ret))
+	}
+
+	return ret[0].Count, nil
+}
+
+// Aggregate sends the aggregation along with the List filters
+func (d *restStore) Aggregate(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	agg store.Aggregation,
+	opt ...options.ListOption) ([]store.AggregateResult, error) {
+
+	log.Printf("aggregate %s", identity)
+
+	if len(identity.Key()) > 0 {
+		return nil, constants.ErrInvalidPath
+	}
+
+	var err error
+	copt := newRestOptions(d)
+	for _, o := range opt {
+		err = o.ApplyFunction()(&copt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = agg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := url.ParseQuery(listParameters(copt))
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := json.Marshal(agg)
+	if err != nil {
+		return nil, err
+	}
+	params.Set(rest.AggregateArg, string(content))
+
+	res, err := processRequest(
+		d,
+		makePathForIdentity(d.BaseURL, identity, params.Encode()),
+		[]byte{},
+		http.MethodGet,
+		copt.Headers)
+
+	if err != nil {
+		return nil, err
 	}
 
-	return marshalledResult, nil
+	ret := []store.AggregateResult{}
+	err = json.Unmarshal(res.Data, &ret)
+	return ret, err
 }
 
 type strippedObject struct {
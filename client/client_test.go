@@ -1,13 +1,19 @@
 package client_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"log"
+	"net/http"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/wazofski/gostorz/client"
 	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/rest"
 	"github.com/wazofski/gostorz/store"
 	"github.com/wazofski/gostorz/store/options"
 )
@@ -96,8 +102,8 @@ var _ = Describe("client", func() {
 		err = stc.Delete(
 			ctx, generated.SecondWorldIdentity(worldName))
 
-		Expect(err).ToNot(BeNil())
-		Expect(err.Error()).To(Equal("http 405"))
+		Expect(errors.Is(err, constants.ErrInvalidMethod)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("http 405"))
 
 		sw := ret.(generated.SecondWorld)
 
@@ -105,8 +111,9 @@ var _ = Describe("client", func() {
 			ctx,
 			sw.Metadata().Identity())
 
-		Expect(err).ToNot(BeNil())
-		Expect(err.Error()).To(Equal("http 405"))
+		// ids do not tell the objects that cannot be deleted
+		Expect(errors.Is(err, constants.ErrNoSuchObject)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("http 404"))
 	})
 
 	It("cannot LIST non-allowed", func() {
@@ -185,4 +192,125 @@ var _ = Describe("client", func() {
 			world.Internal().Description()))
 	})
 
+	It("can fetch the OpenAPI document", func() {
+		resp, err := http.Get("http://localhost:8000" + rest.OpenAPIPath)
+		Expect(err).To(BeNil())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		doc := map[string]interface{}{}
+		err = json.NewDecoder(resp.Body).Decode(&doc)
+		Expect(err).To(BeNil())
+		Expect(doc["openapi"]).To(HavePrefix("3."))
+
+		paths := doc["paths"].(map[string]interface{})
+		Expect(paths).To(HaveKey("/world/{pkey}"))
+		Expect(paths["/world/{pkey}"]).To(HaveKey("put"))
+		Expect(paths["/secondworld"]).To(HaveKey("post"))
+		Expect(paths).ToNot(HaveKey("/thirdworld"))
+		Expect(paths["/secondworld/{pkey}"]).ToNot(HaveKey("put"))
+
+		schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+		Expect(schemas).To(HaveKey("WorldExternal"))
+		Expect(schemas["World"]).To(HaveKeyWithValue("x-primary-key", "external.name"))
+	})
+
+	It("can reject invalid objects", func() {
+		world := generated.WorldFactory()
+		world.External().SetName("invalid")
+		world.External().Nested().SetColor("purple")
+		_, err := stc.Create(ctx, world)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("400"))
+
+		_, err = stc.Get(ctx, generated.WorldIdentity("invalid"))
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("can map errors across the wire", func() {
+		_, err := stc.Get(ctx, generated.WorldIdentity("missing"))
+		Expect(errors.Is(err, constants.ErrNoSuchObject)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("http 404"))
+
+		world := generated.WorldFactory()
+		world.External().SetName("mapped")
+		created, err := stc.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		_, err = stc.Create(ctx, world)
+		Expect(errors.Is(err, constants.ErrObjectExists)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("http 409"))
+
+		_, err = stc.Update(ctx, generated.WorldIdentity("mapped"), world,
+			options.IfRevision(created.Metadata().Revision()+1))
+		Expect(errors.Is(err, constants.ErrConflict)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("http 412"))
+
+		world.External().Nested().SetColor("purple")
+		_, err = stc.Update(ctx, generated.WorldIdentity("mapped"), world)
+		verr := &store.ValidationError{}
+		Expect(errors.As(err, &verr)).To(BeTrue())
+		Expect(verr.Path).To(Equal("external.nested.color"))
+
+		Expect(stc.Delete(ctx, generated.WorldIdentity("mapped"))).To(Succeed())
+	})
+
+	It("can watch over http", func() {
+		wctx, wcancel := context.WithCancel(context.Background())
+		defer wcancel()
+
+		events, err := stc.(store.Watcher).Watch(wctx, generated.WorldIdentity(""))
+		Expect(err).To(BeNil())
+
+		single, err := stc.(store.Watcher).Watch(wctx, generated.WorldIdentity("watched"))
+		Expect(err).To(BeNil())
+
+		world := generated.WorldFactory()
+		world.External().SetName("watched")
+		ret, err := stc.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		other := generated.WorldFactory()
+		other.External().SetName("unwatched")
+		_, err = stc.Create(ctx, other)
+		Expect(err).To(BeNil())
+
+		world = ret.(generated.World)
+		world.External().SetDescription("changed")
+		_, err = stc.Update(ctx, world.Metadata().Identity(), world)
+		Expect(err).To(BeNil())
+
+		err = stc.Delete(ctx, world.Metadata().Identity())
+		Expect(err).To(BeNil())
+
+		var ev store.Event
+		Eventually(events).Should(Receive(&ev))
+		Expect(ev.Type).To(Equal(store.EventCreated))
+		Expect(ev.Object.PrimaryKey()).To(Equal("watched"))
+		Eventually(events).Should(Receive(&ev))
+		Expect(ev.Object.PrimaryKey()).To(Equal("unwatched"))
+
+		Eventually(single).Should(Receive(&ev))
+		Expect(ev.Type).To(Equal(store.EventCreated))
+		Eventually(single).Should(Receive(&ev))
+		Expect(ev.Type).To(Equal(store.EventUpdated))
+		Expect(ev.Object.(generated.World).External().Description()).To(Equal("changed"))
+		Eventually(single).Should(Receive(&ev))
+		Expect(ev.Type).To(Equal(store.EventDeleted))
+		Expect(ev.Object.Metadata().Identity()).To(Equal(world.Metadata().Identity()))
+
+		wcancel()
+		Eventually(events).Should(BeClosed())
+		Eventually(single).Should(BeClosed())
+	})
+
+	It("cannot watch non-allowed", func() {
+		_, err := stc.(store.Watcher).Watch(context.Background(),
+			generated.ThirdWorldIdentity(""))
+		Expect(err).ToNot(BeNil())
+
+		_, err = stc.(store.Watcher).Watch(context.Background(),
+			store.ObjectIdentity("abc"))
+		Expect(err).ToNot(BeNil())
+	})
 })
@@ -0,0 +1,118 @@
+package client_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/wazofski/gostorz/client"
+	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/rest"
+	"github.com/wazofski/gostorz/store"
+)
+
+var _ = Describe("client etags", func() {
+	request := func(method string, header string, etag string) *http.Response {
+		req, err := http.NewRequest(method,
+			"http://localhost:8000/world/tagged", nil)
+		Expect(err).To(BeNil())
+		if len(header) > 0 {
+			req.Header.Set(header, etag)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).To(BeNil())
+		return resp
+	}
+
+	It("can serve conditional requests", func() {
+		world := generated.WorldFactory()
+		world.External().SetName("tagged")
+		created, err := stc.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		etag, err := rest.ETag(created)
+		Expect(err).To(BeNil())
+
+		resp := request(http.MethodGet, "", "")
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("ETag")).To(Equal(etag))
+
+		resp = request(http.MethodGet, "If-None-Match", etag)
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotModified))
+
+		resp = request(http.MethodGet, "If-None-Match", `W/"other"`)
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		for _, method := range []string{http.MethodPut, http.MethodDelete} {
+			resp = request(method, "If-Match", `"other"`)
+			body := rest.ErrorResponse{}
+			Expect(json.NewDecoder(resp.Body).Decode(&body)).To(Succeed())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusPreconditionFailed))
+			Expect(body.Error.Code).To(Equal(constants.CodeConflict))
+		}
+
+		resp = request(http.MethodDelete, "If-Match", etag)
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("can guard read-modify-write", func() {
+		other := store.New(generated.Schema(),
+			client.Factory("http://localhost:8000/"))
+
+		world := generated.WorldFactory()
+		world.External().SetName("tagged")
+		_, err := stc.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		ret, err := stc.Get(ctx, generated.WorldIdentity("tagged"))
+		Expect(err).To(BeNil())
+		Expect(ret.(generated.World).External().Name()).To(Equal("tagged"))
+
+		theirs, err := other.Get(ctx, generated.WorldIdentity("tagged"))
+		Expect(err).To(BeNil())
+		theirs.(generated.World).External().SetDescription("theirs")
+		_, err = other.Update(ctx, generated.WorldIdentity("tagged"), theirs)
+		Expect(err).To(BeNil())
+
+		mine := ret.(generated.World)
+		mine.External().SetDescription("mine")
+		_, err = stc.Update(ctx, generated.WorldIdentity("tagged"), mine)
+		Expect(errors.Is(err, constants.ErrConflict)).To(BeTrue())
+
+		err = stc.Delete(ctx, mine.Metadata().Identity())
+		Expect(errors.Is(err, constants.ErrConflict)).To(BeTrue())
+
+		ret, err = stc.Get(ctx, generated.WorldIdentity("tagged"))
+		Expect(err).To(BeNil())
+		Expect(ret.(generated.World).External().Description()).To(Equal("theirs"))
+
+		ret, err = stc.Get(ctx, generated.WorldIdentity("tagged"))
+		Expect(err).To(BeNil())
+		Expect(ret.(generated.World).External().Description()).To(Equal("theirs"))
+
+		mine = ret.(generated.World)
+		mine.External().SetDescription("mine")
+		_, err = stc.Update(ctx, generated.WorldIdentity("tagged"), mine)
+		Expect(err).To(BeNil())
+
+		theirs.(generated.World).External().SetDescription("forced")
+		_, err = other.Update(ctx, generated.WorldIdentity("tagged"), theirs)
+		Expect(errors.Is(err, constants.ErrConflict)).To(BeTrue())
+		_, err = other.Update(ctx, generated.WorldIdentity("tagged"), theirs,
+			client.Header("If-Match", "*"))
+		Expect(err).To(BeNil())
+
+		Expect(other.Delete(ctx, generated.WorldIdentity("tagged"))).To(Succeed())
+		_, err = stc.Get(ctx, generated.WorldIdentity("tagged"))
+		Expect(errors.Is(err, constants.ErrNoSuchObject)).To(BeTrue())
+	})
+})
@@ -0,0 +1,76 @@
+package client_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/rest"
+	"github.com/wazofski/gostorz/store/options"
+)
+
+var _ = Describe("client fields", func() {
+	get := func(path string) (int, []byte) {
+		resp, err := http.Get("http://localhost:8000/" + path)
+		Expect(err).To(BeNil())
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).To(BeNil())
+
+		return resp.StatusCode, body
+	}
+
+	It("can serve projected objects", func() {
+		world := generated.WorldFactory()
+		world.External().SetName("fielded")
+		world.Internal().SetDescription("large")
+		created, err := stc.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		expected := map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"kind":     "World",
+				"identity": string(created.Metadata().Identity()),
+			},
+			"external": map[string]interface{}{
+				"name": "fielded",
+			},
+		}
+
+		status, body := get("world/fielded?fields=external.name")
+		Expect(status).To(Equal(http.StatusOK))
+		doc := map[string]interface{}{}
+		Expect(json.Unmarshal(body, &doc)).To(Succeed())
+		Expect(doc).To(Equal(expected))
+
+		status, body = get(`world?fields=external.name&kf=["fielded"]`)
+		Expect(status).To(Equal(http.StatusOK))
+		docs := []map[string]interface{}{}
+		Expect(json.Unmarshal(body, &docs)).To(Succeed())
+		Expect(docs).To(Equal([]map[string]interface{}{expected}))
+
+		status, body = get("world/fielded?fields=internal.nothing")
+		Expect(status).To(Equal(http.StatusBadRequest))
+		reply := rest.ErrorResponse{}
+		Expect(json.Unmarshal(body, &reply)).To(Succeed())
+		Expect(reply.Error.Code).To(Equal(constants.CodeInvalidFilter))
+
+		// projections do not replace the version the client holds
+		ret, err := stc.Get(ctx, generated.WorldIdentity("fielded"))
+		Expect(err).To(BeNil())
+		_, err = stc.Get(ctx, generated.WorldIdentity("fielded"),
+			options.Fields("external.name"))
+		Expect(err).To(BeNil())
+
+		ret.(generated.World).External().SetDescription("updated")
+		_, err = stc.Update(ctx, generated.WorldIdentity("fielded"), ret)
+		Expect(err).To(BeNil())
+
+		Expect(stc.Delete(ctx, generated.WorldIdentity("fielded"))).To(Succeed())
+	})
+})
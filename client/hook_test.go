@@ -0,0 +1,80 @@
+package client_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/wazofski/gostorz/client"
+	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/memory"
+	"github.com/wazofski/gostorz/react"
+	"github.com/wazofski/gostorz/rest"
+	"github.com/wazofski/gostorz/store"
+)
+
+var _ = Describe("client after hooks", func() {
+
+	var cancel func()
+	var remote store.Store
+
+	BeforeEach(func() {
+		sch := generated.Schema()
+		hooked := store.New(sch, react.ReactFactory(
+			store.New(sch, memory.Factory()),
+			react.AfterCreate(generated.WorldKind(),
+				func(obj store.Object, st store.Store) error {
+					return errors.New("notification failed")
+				})))
+
+		srv := rest.Server(sch, hooked,
+			rest.TypeMethods(generated.WorldKind(),
+				rest.ActionGet, rest.ActionCreate))
+		cancel = srv.Listen(8004)
+		Eventually(func() error {
+			conn, err := net.Dial("tcp", "localhost:8004")
+			if err == nil {
+				conn.Close()
+			}
+			return err
+		}).Should(BeNil())
+
+		remote = store.New(sch, client.Factory("http://localhost:8004/"))
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("can report committed writes failing after hooks", func() {
+		world := generated.WorldFactory()
+		world.External().SetName("hooked")
+		_, err := remote.Create(ctx, world)
+
+		hookErr := &react.AfterHookError{}
+		Expect(errors.As(err, &hookErr)).To(BeTrue())
+		Expect(hookErr.Err.Error()).To(Equal("notification failed"))
+
+		_, err = remote.Get(ctx, generated.WorldIdentity("hooked"))
+		Expect(err).To(BeNil())
+
+		world.External().SetName("raw")
+		data, err := json.Marshal(world)
+		Expect(err).To(BeNil())
+
+		resp, err := http.Post("http://localhost:8004/world",
+			"application/json", strings.NewReader(string(data)))
+		Expect(err).To(BeNil())
+		defer resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+		body := rest.ErrorResponse{}
+		Expect(json.NewDecoder(resp.Body).Decode(&body)).To(Succeed())
+		Expect(body.Error.Code).To(Equal(constants.CodeAfterHook))
+	})
+})
@@ -0,0 +1,104 @@
+package client_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/rest"
+	"github.com/wazofski/gostorz/store"
+)
+
+var _ = Describe("client patches", func() {
+	request := func(path string, contentType string, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPatch,
+			"http://localhost:8000/"+path, bytes.NewBufferString(body))
+		Expect(err).To(BeNil())
+		if len(contentType) > 0 {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).To(BeNil())
+		return resp
+	}
+
+	It("can PATCH over http", func() {
+		world := generated.WorldFactory()
+		world.External().SetName("patchy")
+		created, err := stc.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		resp := request("world/patchy", string(store.MergePatchType),
+			`{"external": {"description": "merged"}}`)
+		data := generated.WorldFactory()
+		Expect(json.NewDecoder(resp.Body).Decode(&data)).To(Succeed())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(data.External().Description()).To(Equal("merged"))
+		Expect(data.Metadata().Revision()).To(Equal(int64(2)))
+		Expect(data.Metadata().Updated()).ToNot(Equal(created.Metadata().Updated()))
+		Expect(resp.Header.Get("ETag")).ToNot(BeEmpty())
+
+		resp = request("id/"+string(created.Metadata().Identity()), "application/json",
+			`[{"op": "replace", "path": "/external/description", "value": "replaced"}]`)
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		ret, err := stc.Get(ctx, generated.WorldIdentity("patchy"))
+		Expect(err).To(BeNil())
+		Expect(ret.(generated.World).External().Description()).To(Equal("replaced"))
+
+		bad := []struct {
+			contentType string
+			body        string
+		}{
+			{string(store.MergePatchType), `{"metadata": {"identity": "stolen"}}`},
+			{string(store.JSONPatchType), `[{"op": "remove", "path": "/internal"}]`},
+			{string(store.JSONPatchType), `[{"op": "copy", "from": "/metadata", "path": "/external/name"}]`},
+			{"text/plain", `{"external": {}}`},
+		}
+
+		for _, b := range bad {
+			resp = request("world/patchy", b.contentType, b.body)
+			body := rest.ErrorResponse{}
+			Expect(json.NewDecoder(resp.Body).Decode(&body)).To(Succeed())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(body.Error.Code).To(Equal(constants.CodeInvalidPatch))
+		}
+
+		resp = request("world/patchy", string(store.MergePatchType),
+			`{"external": {"nested": {"color": "purple"}}}`)
+		body := rest.ErrorResponse{}
+		Expect(json.NewDecoder(resp.Body).Decode(&body)).To(Succeed())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(body.Error.Code).To(Equal(constants.CodeValidation))
+
+		Expect(stc.Delete(ctx, generated.WorldIdentity("patchy"))).To(Succeed())
+	})
+
+	It("cannot PATCH non-allowed", func() {
+		resp := request("secondworld/patchy", string(store.MergePatchType),
+			`{"external": {"description": "merged"}}`)
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+
+		patcher, ok := stc.(store.Patcher)
+		Expect(ok).To(BeTrue())
+
+		_, err := patcher.Patch(ctx, generated.SecondWorldIdentity("patchy"),
+			store.MergePatch([]byte(`{"external": {"description": "merged"}}`)))
+		Expect(errors.Is(err, constants.ErrInvalidMethod)).To(BeTrue())
+
+		_, err = patcher.Patch(ctx, generated.WorldIdentity("patchy"),
+			store.JSONPatch([]byte(`[{"op": "remove", "path": "/metadata"}]`)))
+		Expect(errors.Is(err, constants.ErrInvalidPatch)).To(BeTrue())
+	})
+})
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/wazofski/gostorz/store"
+)
+
+// versionsLimit and versionsBytes bound the versions the client holds,
+// the least recently used ones are dropped first
+const versionsLimit = 1024
+const versionsBytes = 16 << 20
+
+// _Version is the last fetched content of an object and its ETag,
+// known by both its id and its kind/primary key paths
+type _Version struct {
+	ETag    string
+	Data    []byte
+	Keys    []string
+	Element *list.Element
+}
+
+type _Versions struct {
+	Lock     sync.Mutex
+	Entries  map[string]*_Version
+	Recent   *list.List
+	Bytes    int
+	Limit    int
+	MaxBytes int
+}
+
+func newVersions(limit int, maxBytes int) *_Versions {
+	return &_Versions{
+		Entries:  make(map[string]*_Version),
+		Recent:   list.New(),
+		Limit:    limit,
+		MaxBytes: maxBytes,
+	}
+}
+
+func (v *_Versions) Get(identity store.ObjectIdentity) *_Version {
+	v.Lock.Lock()
+	defer v.Lock.Unlock()
+
+	version := v.Entries[identity.Path()]
+	if version != nil {
+		v.Recent.MoveToFront(version.Element)
+	}
+
+	return version
+}
+
+// Remember keeps the object content served with the ETag
+func (v *_Versions) Remember(identity store.ObjectIdentity, obj store.Object, etag string, data []byte) {
+	if len(etag) == 0 || obj == nil {
+		v.Forget(identity)
+		return
+	}
+
+	keys := []string{
+		obj.Metadata().Identity().Path(),
+		strings.ToLower(obj.Metadata().Kind()) + "/" + obj.PrimaryKey(),
+	}
+	if len(identity.Key()) > 0 && identity.Path() != keys[0] && identity.Path() != keys[1] {
+		keys = append(keys, identity.Path())
+	}
+
+	v.Lock.Lock()
+	defer v.Lock.Unlock()
+
+	for _, k := range keys {
+		v.forget(k)
+	}
+
+	version := &_Version{
+		ETag: etag,
+		Data: data,
+		Keys: keys,
+	}
+	for _, k := range keys {
+		v.Entries[k] = version
+	}
+
+	version.Element = v.Recent.PushFront(version)
+	v.Bytes += len(data)
+	for v.Recent.Len() > v.Limit || v.Bytes > v.MaxBytes {
+		v.forget(v.Recent.Back().Value.(*_Version).Keys[0])
+	}
+}
+
+// Forget drops the object under all of its paths
+func (v *_Versions) Forget(identity store.ObjectIdentity) {
+	v.Lock.Lock()
+	defer v.Lock.Unlock()
+
+	v.forget(identity.Path())
+}
+
+func (v *_Versions) forget(key string) {
+	version, ok := v.Entries[key]
+	if !ok {
+		return
+	}
+
+	for _, k := range version.Keys {
+		delete(v.Entries, k)
+	}
+
+	v.Recent.Remove(version.Element)
+	v.Bytes -= len(version.Data)
+}
+
+// conditional sets the precondition header unless
+// the caller already set it for the request
+func conditional(headers map[string]string, key string, version *_Version) {
+	if version == nil {
+		return
+	}
+
+	for k := range headers {
+		if http.CanonicalHeaderKey(k) == key {
+			return
+		}
+	}
+
+	headers[key] = version.ETag
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/store"
+)
+
+var _ = Describe("versions", func() {
+
+	world := func(name string) store.Object {
+		w := generated.WorldFactory()
+		w.External().SetName(name)
+		return w
+	}
+
+	It("can drop the least recently used versions", func() {
+		versions := newVersions(2, 1<<20)
+		for _, name := range []string{"a", "b"} {
+			versions.Remember(generated.WorldIdentity(name), world(name), name, []byte(name))
+		}
+
+		Expect(versions.Get(generated.WorldIdentity("a"))).ToNot(BeNil())
+		versions.Remember(generated.WorldIdentity("c"), world("c"), "c", []byte("c"))
+
+		Expect(versions.Get(generated.WorldIdentity("b"))).To(BeNil())
+		Expect(versions.Get(generated.WorldIdentity("a")).ETag).To(Equal("a"))
+		Expect(versions.Get(generated.WorldIdentity("c")).ETag).To(Equal("c"))
+		Expect(len(versions.Entries)).To(Equal(4))
+	})
+
+	It("can bound the held content", func() {
+		versions := newVersions(10, 8)
+		versions.Remember(generated.WorldIdentity("a"), world("a"), "a", []byte("12345"))
+		versions.Remember(generated.WorldIdentity("b"), world("b"), "b", []byte("12345"))
+
+		Expect(versions.Get(generated.WorldIdentity("a"))).To(BeNil())
+		Expect(versions.Get(generated.WorldIdentity("b"))).ToNot(BeNil())
+		Expect(versions.Bytes).To(Equal(5))
+
+		versions.Forget(generated.WorldIdentity("b"))
+		Expect(versions.Bytes).To(Equal(0))
+		Expect(versions.Recent.Len()).To(Equal(0))
+	})
+})
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/rest"
+	"github.com/wazofski/gostorz/store"
+	"github.com/wazofski/gostorz/store/options"
+	"github.com/wazofski/gostorz/utils"
+)
+
+// maxEventSize bounds the size of a single streamed object
+const maxEventSize = 16 << 20
+
+// Watch streams the events of a kind, or of a single object,
+// from the server. The channel is closed when the stream ends
+func (d *restStore) Watch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.WatchOption) (<-chan store.Event, error) {
+
+	log.Printf("watch %s", identity.Path())
+
+	if identity.Type() == "id" {
+		return nil, constants.ErrNotSupported
+	}
+
+	copt := newRestOptions(d)
+	for _, o := range opt {
+		err := o.ApplyFunction()(&copt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(identity.Key()) > 0 {
+		options.KeyFilter(identity.Key()).ApplyFunction()(&copt)
+	}
+
+	params, err := url.ParseQuery(listParameters(copt))
+	if err != nil {
+		return nil, err
+	}
+	params.Set(rest.WatchArg, "true")
+
+	path := makePathForIdentity(d.BaseURL,
+		store.ObjectIdentity(identity.Type()+"/"), params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range copt.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
+		defer resp.Body.Close()
+		data, _ := utils.ReadStream(resp.Body)
+		return nil, responseError(fmt.Errorf("http %d", resp.StatusCode), data)
+	}
+
+	events := make(chan store.Event)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+
+		typ, data := "", ""
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event:"):
+				typ = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			case strings.HasPrefix(line, "data:"):
+				data += strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			case len(line) == 0 && len(data) > 0:
+				ev := store.Event{Type: store.EventType(typ)}
+				obj, err := utils.UnmarshalObject(
+					[]byte(data), d.Schema, utils.ObjeectKind([]byte(data)))
+				typ, data = "", ""
+				if err != nil {
+					log.Printf("watch %s: %s", identity.Path(), err)
+					continue
+				}
+				ev.Object = obj
+
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
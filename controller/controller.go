@@ -0,0 +1,232 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/internal/logger"
+	"github.com/wazofski/gostorz/store"
+)
+
+var log = logger.Factory("controller")
+
+const (
+	DefaultResync      = 10 * time.Minute
+	DefaultBaseBackoff = 5 * time.Millisecond
+	DefaultMaxBackoff  = 5 * time.Minute
+)
+
+// Result tells the controller when to reconcile the identity again
+type Result struct {
+	Requeue      bool
+	RequeueAfter time.Duration
+}
+
+// Reconciler converges the state of the identity, errors
+// requeue it with exponential backoff
+type Reconciler func(ctx context.Context, identity store.ObjectIdentity) (Result, error)
+
+// Config of the controller, zero values use the defaults.
+// RateLimit caps the reconciliations per second, 0 is unlimited
+type Config struct {
+	Workers     int
+	Resync      time.Duration
+	RateLimit   float64
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+type Controller interface {
+	// Run reconciles until the context is cancelled
+	Run(context.Context) error
+	// Enqueue schedules the identity for reconciliation
+	Enqueue(store.ObjectIdentity)
+}
+
+type controller struct {
+	Store     store.Store
+	Kind      string
+	Reconcile Reconciler
+	Config    Config
+	Queue     *_Queue
+}
+
+// New makes a controller reconciling the objects of the kind
+// on every change watched and on every periodic resync
+func New(st store.Store, kind string, reconcile Reconciler, config ...Config) Controller {
+	if len(config) > 1 {
+		log.Fatal(fmt.Errorf("multiple configs cannot be set"))
+	}
+
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.Resync <= 0 {
+		cfg.Resync = DefaultResync
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = DefaultBaseBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+
+	limiter := _Limiter{}
+	if cfg.RateLimit > 0 {
+		limiter.Interval = time.Duration(float64(time.Second) / cfg.RateLimit)
+	}
+
+	return &controller{
+		Store:     st,
+		Kind:      strings.ToLower(kind),
+		Reconcile: reconcile,
+		Config:    cfg,
+		Queue: newQueue(_Backoff{
+			Base: cfg.BaseBackoff,
+			Max:  cfg.MaxBackoff,
+		}, limiter),
+	}
+}
+
+func (c *controller) Enqueue(identity store.ObjectIdentity) {
+	c.Queue.Add(identity)
+}
+
+func (c *controller) Run(ctx context.Context) error {
+	log.Printf("run %s", c.Kind)
+
+	err := c.resync(ctx)
+	if err != nil {
+		return err
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < c.Config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.work(ctx)
+		}()
+	}
+
+	go c.watch(ctx)
+
+	ticker := time.NewTicker(c.Config.Resync)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := c.resync(ctx)
+			if err != nil {
+				log.Printf("resync %s failed: %s", c.Kind, err)
+			}
+		case <-ctx.Done():
+			c.Queue.ShutDown()
+			wg.Wait()
+			return nil
+		}
+	}
+}
+
+// resync queues every object of the kind
+func (c *controller) resync(ctx context.Context) error {
+	list, err := c.Store.List(ctx, store.ObjectIdentity(c.Kind+"/"))
+	if err != nil {
+		return err
+	}
+
+	for _, o := range list {
+		c.Queue.Add(c.identity(o))
+	}
+
+	return nil
+}
+
+// watch queues the changed objects, watching again after a
+// resync whenever the watcher falls behind. Watches closing
+// without events are retried with the backoff of the queue
+func (c *controller) watch(ctx context.Context) {
+	watcher, ok := c.Store.(store.Watcher)
+	if !ok {
+		log.Printf("watch %s: %s, resyncing only", c.Kind, constants.ErrNotSupported)
+		return
+	}
+
+	failures := 0
+	for ctx.Err() == nil {
+		events, err := watcher.Watch(ctx, store.ObjectIdentity(c.Kind+"/"))
+		if err != nil {
+			log.Printf("watch %s failed: %s, resyncing only", c.Kind, err)
+			return
+		}
+
+		for ev := range events {
+			failures = 0
+			c.Queue.Add(c.identity(ev.Object))
+		}
+
+		timer := time.NewTimer(c.Queue.Backoff.Delay(failures))
+		failures++
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		err = c.resync(ctx)
+		if err != nil {
+			log.Printf("resync %s failed: %s", c.Kind, err)
+		}
+	}
+}
+
+func (c *controller) work(ctx context.Context) {
+	for {
+		identity, ok := c.Queue.Get()
+		if !ok {
+			return
+		}
+
+		c.process(ctx, identity)
+		c.Queue.Done(identity)
+	}
+}
+
+func (c *controller) process(ctx context.Context, identity store.ObjectIdentity) {
+	if c.Queue.Wait(ctx) != nil {
+		return
+	}
+
+	res, err := c.Reconcile(ctx, identity)
+	switch {
+	case err != nil:
+		if !errors.Is(err, context.Canceled) {
+			log.Printf("reconcile %s failed: %s", identity.Path(), err)
+		}
+		c.Queue.AddFailed(identity)
+	case res.RequeueAfter > 0:
+		c.Queue.Forget(identity)
+		c.Queue.AddAfter(identity, res.RequeueAfter)
+	case res.Requeue:
+		c.Queue.AddFailed(identity)
+	default:
+		c.Queue.Forget(identity)
+	}
+}
+
+func (c *controller) identity(obj store.Object) store.ObjectIdentity {
+	return store.ObjectIdentity(fmt.Sprintf("%s/%s", c.Kind, obj.PrimaryKey()))
+}
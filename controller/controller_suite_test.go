@@ -0,0 +1,40 @@
+package controller_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/wazofski/gostorz/store"
+	"github.com/wazofski/gostorz/store/options"
+)
+
+func TestController(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "controller suite")
+}
+
+// unwatchedStore hides the Watcher of the underlying store
+type unwatchedStore struct {
+	store.Store
+}
+
+// closingStore counts the watches, closing them at once
+type closingStore struct {
+	store.Store
+	Watches int32
+}
+
+func (d *closingStore) Watch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.WatchOption) (<-chan store.Event, error) {
+
+	atomic.AddInt32(&d.Watches, 1)
+	events := make(chan store.Event)
+	close(events)
+
+	return events, nil
+}
@@ -0,0 +1,226 @@
+package controller_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/wazofski/gostorz/controller"
+	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/memory"
+	"github.com/wazofski/gostorz/store"
+)
+
+var _ = Describe("controller", func() {
+
+	var mem store.Store
+	var ctx context.Context
+	var cancel context.CancelFunc
+	var done chan error
+
+	create := func(name string) {
+		world := generated.WorldFactory()
+		world.External().SetName(name)
+		_, err := mem.Create(context.Background(), world)
+		Expect(err).To(BeNil())
+	}
+
+	run := func(ctrl controller.Controller) {
+		done = make(chan error, 1)
+		go func() {
+			done <- ctrl.Run(ctx)
+		}()
+	}
+
+	// reconciled records the reconciliations of every identity
+	type reconciled struct {
+		Lock  sync.Mutex
+		Calls map[string]int
+	}
+
+	record := func(r *reconciled, identity store.ObjectIdentity) {
+		r.Lock.Lock()
+		defer r.Lock.Unlock()
+		r.Calls[identity.Path()]++
+	}
+
+	calls := func(r *reconciled, identity store.ObjectIdentity) func() int {
+		return func() int {
+			r.Lock.Lock()
+			defer r.Lock.Unlock()
+			return r.Calls[identity.Path()]
+		}
+	}
+
+	BeforeEach(func() {
+		mem = store.New(generated.Schema(), memory.Factory())
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+
+	It("can reconcile existing and changed objects", func() {
+		create("existing")
+
+		r := &reconciled{Calls: map[string]int{}}
+		run(controller.New(mem, generated.WorldKind(),
+			func(ctx context.Context, identity store.ObjectIdentity) (controller.Result, error) {
+				record(r, identity)
+				return controller.Result{}, nil
+			}))
+
+		Eventually(calls(r, generated.WorldIdentity("existing"))).Should(Equal(1))
+
+		create("changed")
+		Eventually(calls(r, generated.WorldIdentity("changed"))).Should(Equal(1))
+
+		err := mem.Delete(context.Background(), generated.WorldIdentity("changed"))
+		Expect(err).To(BeNil())
+		Eventually(calls(r, generated.WorldIdentity("changed"))).Should(Equal(2))
+
+		// other kinds are not reconciled
+		second := generated.SecondWorldFactory()
+		second.External().SetName("other")
+		_, err = mem.Create(context.Background(), second)
+		Expect(err).To(BeNil())
+		Consistently(calls(r, generated.SecondWorldIdentity("other")),
+			100*time.Millisecond).Should(Equal(0))
+	})
+
+	It("can requeue failures with backoff", func() {
+		create("failing")
+
+		r := &reconciled{Calls: map[string]int{}}
+		start := time.Now()
+		run(controller.New(mem, generated.WorldKind(),
+			func(ctx context.Context, identity store.ObjectIdentity) (controller.Result, error) {
+				record(r, identity)
+				if calls(r, identity)() < 4 {
+					return controller.Result{}, fmt.Errorf("not yet")
+				}
+				return controller.Result{}, nil
+			}, controller.Config{BaseBackoff: 20 * time.Millisecond}))
+
+		Eventually(calls(r, generated.WorldIdentity("failing"))).Should(Equal(4))
+		Consistently(calls(r, generated.WorldIdentity("failing")),
+			200*time.Millisecond).Should(Equal(4))
+
+		// 20 + 40 + 80 ms
+		Expect(time.Since(start)).To(BeNumerically(">=", 140*time.Millisecond))
+	})
+
+	It("can requeue after a delay", func() {
+		create("periodic")
+
+		r := &reconciled{Calls: map[string]int{}}
+		run(controller.New(mem, generated.WorldKind(),
+			func(ctx context.Context, identity store.ObjectIdentity) (controller.Result, error) {
+				record(r, identity)
+				return controller.Result{RequeueAfter: 20 * time.Millisecond}, nil
+			}))
+
+		Eventually(calls(r, generated.WorldIdentity("periodic"))).Should(BeNumerically(">=", 3))
+	})
+
+	It("can resync stores without watch", func() {
+		create("resynced")
+
+		r := &reconciled{Calls: map[string]int{}}
+		run(controller.New(&unwatchedStore{Store: mem}, generated.WorldKind(),
+			func(ctx context.Context, identity store.ObjectIdentity) (controller.Result, error) {
+				record(r, identity)
+				return controller.Result{}, nil
+			}, controller.Config{Resync: 50 * time.Millisecond}))
+
+		Eventually(calls(r, generated.WorldIdentity("resynced"))).Should(BeNumerically(">=", 3))
+
+		create("late")
+		Eventually(calls(r, generated.WorldIdentity("late"))).Should(BeNumerically(">=", 1))
+	})
+
+	It("can back off watches closing at once", func() {
+		create("closed")
+
+		closing := &closingStore{Store: mem}
+		r := &reconciled{Calls: map[string]int{}}
+		run(controller.New(closing, generated.WorldKind(),
+			func(ctx context.Context, identity store.ObjectIdentity) (controller.Result, error) {
+				record(r, identity)
+				return controller.Result{}, nil
+			}, controller.Config{BaseBackoff: 20 * time.Millisecond}))
+
+		Eventually(calls(r, generated.WorldIdentity("closed"))).Should(Equal(1))
+		time.Sleep(300 * time.Millisecond)
+
+		// 20 + 40 + 80 + 160 ms
+		Expect(atomic.LoadInt32(&closing.Watches)).To(BeNumerically("<=", 5))
+	})
+
+	It("can run concurrent workers without overlapping identities", func() {
+		for i := 0; i < 8; i++ {
+			create(fmt.Sprintf("worker-%d", i))
+		}
+
+		var running, peak int32
+		overlap := sync.Map{}
+		r := &reconciled{Calls: map[string]int{}}
+		ctrl := controller.New(mem, generated.WorldKind(),
+			func(ctx context.Context, identity store.ObjectIdentity) (controller.Result, error) {
+				defer GinkgoRecover()
+
+				_, busy := overlap.LoadOrStore(identity.Path(), true)
+				Expect(busy).To(BeFalse())
+				defer overlap.Delete(identity.Path())
+
+				now := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+				for {
+					p := atomic.LoadInt32(&peak)
+					if now <= p || atomic.CompareAndSwapInt32(&peak, p, now) {
+						break
+					}
+				}
+
+				time.Sleep(50 * time.Millisecond)
+				record(r, identity)
+				return controller.Result{}, nil
+			}, controller.Config{Workers: 4})
+		run(ctrl)
+
+		// enqueueing while reconciling queues the identity once more
+		ctrl.Enqueue(generated.WorldIdentity("worker-0"))
+		ctrl.Enqueue(generated.WorldIdentity("worker-0"))
+
+		for i := 0; i < 8; i++ {
+			Eventually(calls(r, generated.WorldIdentity(fmt.Sprintf("worker-%d", i)))).
+				Should(BeNumerically(">=", 1))
+		}
+		Expect(atomic.LoadInt32(&peak)).To(Equal(int32(4)))
+	})
+
+	It("can rate limit reconciliations", func() {
+		for i := 0; i < 5; i++ {
+			create(fmt.Sprintf("limited-%d", i))
+		}
+
+		var total int32
+		start := time.Now()
+		run(controller.New(mem, generated.WorldKind(),
+			func(ctx context.Context, identity store.ObjectIdentity) (controller.Result, error) {
+				atomic.AddInt32(&total, 1)
+				return controller.Result{}, nil
+			}, controller.Config{Workers: 5, RateLimit: 20}))
+
+		Eventually(func() int32 {
+			return atomic.LoadInt32(&total)
+		}).Should(Equal(int32(5)))
+		Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+	})
+})
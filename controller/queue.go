@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/wazofski/gostorz/store"
+)
+
+// _Queue hands identities out to the workers.
+// Pending identities are queued once and identities being
+// reconciled are queued again only once they are done.
+type _Queue struct {
+	Lock       sync.Mutex
+	Cond       *sync.Cond
+	Items      []store.ObjectIdentity
+	Pending    map[string]bool
+	Processing map[string]bool
+	Failures   map[string]int
+	Backoff    _Backoff
+	Limiter    _Limiter
+	Closed     bool
+}
+
+// _Backoff doubles the requeue delay of every failure up to Max
+type _Backoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay returns the delay following the failures
+func (b _Backoff) Delay(failures int) time.Duration {
+	delay := b.Base
+	for i := 0; i < failures && delay < b.Max; i++ {
+		delay *= 2
+	}
+	if delay > b.Max {
+		delay = b.Max
+	}
+
+	return delay
+}
+
+// _Limiter spaces out the reconciliations by Interval
+type _Limiter struct {
+	Interval time.Duration
+	Next     time.Time
+}
+
+func newQueue(backoff _Backoff, limiter _Limiter) *_Queue {
+	q := &_Queue{
+		Pending:    make(map[string]bool),
+		Processing: make(map[string]bool),
+		Failures:   make(map[string]int),
+		Backoff:    backoff,
+		Limiter:    limiter,
+	}
+	q.Cond = sync.NewCond(&q.Lock)
+
+	return q
+}
+
+// Add queues the identity unless it is already pending
+func (q *_Queue) Add(identity store.ObjectIdentity) {
+	q.Lock.Lock()
+	defer q.Lock.Unlock()
+
+	key := identity.Path()
+	if q.Closed || q.Pending[key] {
+		return
+	}
+
+	q.Pending[key] = true
+	if q.Processing[key] {
+		return
+	}
+
+	q.Items = append(q.Items, identity)
+	q.Cond.Signal()
+}
+
+// AddAfter queues the identity once the delay passed
+func (q *_Queue) AddAfter(identity store.ObjectIdentity, delay time.Duration) {
+	if delay <= 0 {
+		q.Add(identity)
+		return
+	}
+
+	time.AfterFunc(delay, func() {
+		q.Add(identity)
+	})
+}
+
+// AddFailed queues the identity with exponential backoff
+func (q *_Queue) AddFailed(identity store.ObjectIdentity) {
+	q.Lock.Lock()
+	key := identity.Path()
+	failures := q.Failures[key]
+	q.Failures[key] = failures + 1
+	q.Lock.Unlock()
+
+	q.AddAfter(identity, q.Backoff.Delay(failures))
+}
+
+// Forget resets the backoff of the identity
+func (q *_Queue) Forget(identity store.ObjectIdentity) {
+	q.Lock.Lock()
+	defer q.Lock.Unlock()
+
+	delete(q.Failures, identity.Path())
+}
+
+// Get blocks until an identity is available and
+// returns false once the queue is shut down
+func (q *_Queue) Get() (store.ObjectIdentity, bool) {
+	q.Lock.Lock()
+	defer q.Lock.Unlock()
+
+	for len(q.Items) == 0 && !q.Closed {
+		q.Cond.Wait()
+	}
+
+	if q.Closed {
+		return "", false
+	}
+
+	identity := q.Items[0]
+	q.Items = q.Items[1:]
+
+	key := identity.Path()
+	delete(q.Pending, key)
+	q.Processing[key] = true
+
+	return identity, true
+}
+
+// Done marks the identity reconciled, queueing it
+// again when it was added in the meantime
+func (q *_Queue) Done(identity store.ObjectIdentity) {
+	q.Lock.Lock()
+	defer q.Lock.Unlock()
+
+	key := identity.Path()
+	delete(q.Processing, key)
+
+	if q.Pending[key] && !q.Closed {
+		q.Items = append(q.Items, identity)
+		q.Cond.Signal()
+	}
+}
+
+// Wait blocks until the rate limit allows the next reconciliation
+func (q *_Queue) Wait(ctx context.Context) error {
+	if q.Limiter.Interval <= 0 {
+		return nil
+	}
+
+	q.Lock.Lock()
+	now := time.Now()
+	if q.Limiter.Next.Before(now) {
+		q.Limiter.Next = now
+	}
+	delay := q.Limiter.Next.Sub(now)
+	q.Limiter.Next = q.Limiter.Next.Add(q.Limiter.Interval)
+	q.Lock.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ShutDown releases the workers waiting for identities
+func (q *_Queue) ShutDown() {
+	q.Lock.Lock()
+	defer q.Lock.Unlock()
+
+	q.Closed = true
+	q.Cond.Broadcast()
+}
@@ -1,17 +1,165 @@
 package filter
 
-// eq Equal
-// ne Not equal
-// lt Less than
-// le Less than or equal
-// gt Greater than
-// ge Greater than or equal
-// in Inside an array or map
-
-// and
-// or
-// not
-
-// convert to mongo
-// convert to memory
-// convert to sql
+import (
+	"errors"
+	"fmt"
+)
+
+type Operator string
+
+const (
+	OpEq  Operator = "eq"  // Equal
+	OpNe  Operator = "ne"  // Not equal
+	OpLt  Operator = "lt"  // Less than
+	OpLe  Operator = "le"  // Less than or equal
+	OpGt  Operator = "gt"  // Greater than
+	OpGe  Operator = "ge"  // Greater than or equal
+	OpIn  Operator = "in"  // Inside an array of values
+	OpAnd Operator = "and" // All arguments match
+	OpOr  Operator = "or"  // Any argument matches
+	OpNot Operator = "not" // Argument does not match
+)
+
+var ErrInvalidExpression = errors.New("invalid filter expression")
+
+type Expression struct {
+	Op    Operator      `json:"op"`
+	Key   string        `json:"key,omitempty"`
+	Value interface{}   `json:"value,omitempty"`
+	Args  []*Expression `json:"args,omitempty"`
+}
+
+func Eq(key string, val interface{}) *Expression {
+	return compare(OpEq, key, val)
+}
+
+func Ne(key string, val interface{}) *Expression {
+	return compare(OpNe, key, val)
+}
+
+func Lt(key string, val interface{}) *Expression {
+	return compare(OpLt, key, val)
+}
+
+func Le(key string, val interface{}) *Expression {
+	return compare(OpLe, key, val)
+}
+
+func Gt(key string, val interface{}) *Expression {
+	return compare(OpGt, key, val)
+}
+
+func Ge(key string, val interface{}) *Expression {
+	return compare(OpGe, key, val)
+}
+
+func In(key string, vals ...interface{}) *Expression {
+	return compare(OpIn, key, vals)
+}
+
+func And(args ...*Expression) *Expression {
+	return &Expression{
+		Op:   OpAnd,
+		Args: args,
+	}
+}
+
+func Or(args ...*Expression) *Expression {
+	return &Expression{
+		Op:   OpOr,
+		Args: args,
+	}
+}
+
+func Not(arg *Expression) *Expression {
+	return &Expression{
+		Op:   OpNot,
+		Args: []*Expression{arg},
+	}
+}
+
+func compare(op Operator, key string, val interface{}) *Expression {
+	return &Expression{
+		Op:    op,
+		Key:   key,
+		Value: val,
+	}
+}
+
+func (e *Expression) Validate() error {
+	if e == nil {
+		return ErrInvalidExpression
+	}
+
+	switch e.Op {
+	case OpEq, OpNe, OpLt, OpLe, OpGt, OpGe:
+		if len(e.Key) == 0 {
+			return fmt.Errorf("%w: %s without key", ErrInvalidExpression, e.Op)
+		}
+		if !isScalar(e.Value) {
+			return fmt.Errorf("%w: %s value must be scalar", ErrInvalidExpression, e.Op)
+		}
+	case OpIn:
+		if len(e.Key) == 0 {
+			return fmt.Errorf("%w: %s without key", ErrInvalidExpression, e.Op)
+		}
+		vals, ok := e.Value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%w: %s value must be a list", ErrInvalidExpression, e.Op)
+		}
+		for _, v := range vals {
+			if !isScalar(v) {
+				return fmt.Errorf("%w: %s values must be scalar", ErrInvalidExpression, e.Op)
+			}
+		}
+	case OpAnd, OpOr:
+		if len(e.Args) == 0 {
+			return fmt.Errorf("%w: %s without arguments", ErrInvalidExpression, e.Op)
+		}
+		for _, a := range e.Args {
+			err := a.Validate()
+			if err != nil {
+				return err
+			}
+		}
+	case OpNot:
+		if len(e.Args) != 1 {
+			return fmt.Errorf("%w: %s takes one argument", ErrInvalidExpression, e.Op)
+		}
+		return e.Args[0].Validate()
+	default:
+		return fmt.Errorf("%w: unknown operator %s", ErrInvalidExpression, e.Op)
+	}
+
+	return nil
+}
+
+// Keys returns every property path referenced by the expression
+func (e *Expression) Keys() []string {
+	if e == nil {
+		return nil
+	}
+
+	if len(e.Key) > 0 {
+		return []string{e.Key}
+	}
+
+	res := []string{}
+	for _, a := range e.Args {
+		res = append(res, a.Keys()...)
+	}
+
+	return res
+}
+
+func isScalar(val interface{}) bool {
+	switch val.(type) {
+	case string, bool, nil,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,180 @@
+package filter
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+)
+
+// Match evaluates the expression against any JSON serializable object
+func (e *Expression) Match(obj interface{}) (bool, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return false, err
+	}
+
+	jsn, err := gabs.ParseJSON(data)
+	if err != nil {
+		return false, err
+	}
+
+	return e.match(jsn), nil
+}
+
+func (e *Expression) match(jsn *gabs.Container) bool {
+	switch e.Op {
+	case OpAnd:
+		for _, a := range e.Args {
+			if !a.match(jsn) {
+				return false
+			}
+		}
+		return true
+	case OpOr:
+		for _, a := range e.Args {
+			if a.match(jsn) {
+				return true
+			}
+		}
+		return false
+	case OpNot:
+		return !e.Args[0].match(jsn)
+	}
+
+	path := strings.Split(e.Key, ".")
+	if !jsn.Exists(path...) {
+		return false
+	}
+	val := jsn.Search(path...).Data()
+
+	switch e.Op {
+	case OpEq:
+		return equal(val, e.Value)
+	case OpNe:
+		return !equal(val, e.Value)
+	case OpIn:
+		vals, _ := e.Value.([]interface{})
+		for _, v := range vals {
+			if equal(val, v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	res, ok := order(val, e.Value)
+	if !ok {
+		return false
+	}
+
+	switch e.Op {
+	case OpLt:
+		return res < 0
+	case OpLe:
+		return res <= 0
+	case OpGt:
+		return res > 0
+	case OpGe:
+		return res >= 0
+	}
+
+	return false
+}
+
+func equal(a interface{}, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	if ab, ok := a.(bool); ok {
+		bb, ok := b.(bool)
+		return ok && ab == bb
+	}
+
+	res, ok := order(a, b)
+	return ok && res == 0
+}
+
+// Compare orders the values the way the filters compare them,
+// with nil before any value and false before true.
+// Values of different types are not comparable
+func Compare(a interface{}, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		switch {
+		case a != nil:
+			return 1, true
+		case b != nil:
+			return -1, true
+		}
+		return 0, true
+	}
+
+	if ab, ok := a.(bool); ok {
+		bb, ok := b.(bool)
+		switch {
+		case !ok:
+			return 0, false
+		case ab == bb:
+			return 0, true
+		case bb:
+			return -1, true
+		}
+		return 1, true
+	}
+
+	return order(a, b)
+}
+
+func order(a interface{}, b interface{}) (int, bool) {
+	an, aok := number(a)
+	bn, bok := number(b)
+	if aok && bok {
+		switch {
+		case an < bn:
+			return -1, true
+		case an > bn:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	as, aok := a.(string)
+	bs, bok := b.(string)
+	if aok && bok {
+		return strings.Compare(as, bs), true
+	}
+
+	return 0, false
+}
+
+func number(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+
+	return 0, false
+}
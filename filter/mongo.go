@@ -0,0 +1,36 @@
+package filter
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var mongoOperators = map[Operator]string{
+	OpEq: "$eq",
+	OpNe: "$ne",
+	OpLt: "$lt",
+	OpLe: "$lte",
+	OpGt: "$gt",
+	OpGe: "$gte",
+	OpIn: "$in",
+}
+
+// BSON compiles the expression into a mongo query.
+// The prefix is prepended to every property path.
+func (e *Expression) BSON(prefix string) bson.M {
+	switch e.Op {
+	case OpAnd, OpOr:
+		a := bson.A{}
+		for _, arg := range e.Args {
+			a = append(a, arg.BSON(prefix))
+		}
+		return bson.M{fmt.Sprintf("$%s", e.Op): a}
+	case OpNot:
+		return bson.M{"$nor": bson.A{e.Args[0].BSON(prefix)}}
+	}
+
+	return bson.M{
+		fmt.Sprintf("%s%s", prefix, e.Key): bson.M{mongoOperators[e.Op]: e.Value},
+	}
+}
@@ -0,0 +1,56 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+var sqlOperators = map[Operator]string{
+	OpEq: "=",
+	OpNe: "<>",
+	OpLt: "<",
+	OpLe: "<=",
+	OpGt: ">",
+	OpGe: ">=",
+}
+
+// SQL compiles the expression into a WHERE clause fragment with ? placeholders.
+// The column function maps property paths to SQL expressions and their arguments.
+func (e *Expression) SQL(column func(string) (string, []interface{})) (string, []interface{}) {
+	switch e.Op {
+	case OpAnd, OpOr:
+		parts := []string{}
+		args := []interface{}{}
+		for _, a := range e.Args {
+			q, qa := a.SQL(column)
+			parts = append(parts, q)
+			args = append(args, qa...)
+		}
+		return fmt.Sprintf("(%s)",
+			strings.Join(parts, fmt.Sprintf(" %s ", strings.ToUpper(string(e.Op))))), args
+	case OpNot:
+		q, args := e.Args[0].SQL(column)
+		return fmt.Sprintf("NOT %s", q), args
+	case OpIn:
+		vals, _ := e.Value.([]interface{})
+		if len(vals) == 0 {
+			return "(1 = 0)", []interface{}{}
+		}
+		col, args := column(e.Key)
+		marks := strings.TrimSuffix(strings.Repeat("?, ", len(vals)), ", ")
+		return fmt.Sprintf("(%s IN (%s))", col, marks), append(args, vals...)
+	}
+
+	col, args := column(e.Key)
+	if e.Value == nil {
+		switch e.Op {
+		case OpEq:
+			return fmt.Sprintf("(%s IS NULL)", col), args
+		case OpNe:
+			return fmt.Sprintf("(%s IS NOT NULL)", col), args
+		}
+	}
+
+	return fmt.Sprintf("(%s %s ?)", col, sqlOperators[e.Op]),
+		append(args, e.Value)
+}
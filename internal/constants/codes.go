@@ -0,0 +1,70 @@
+package constants
+
+import (
+	"errors"
+)
+
+// Stable codes of the sentinel errors crossing the wire
+const (
+	CodeNotFound         = "not_found"
+	CodeAlreadyExists    = "already_exists"
+	CodeConflict         = "conflict"
+	CodeInvalidFilter    = "invalid_filter"
+	CodeInvalidObject    = "invalid_object"
+	CodeInvalidPath      = "invalid_path"
+	CodeInvalidPatch     = "invalid_patch"
+	CodeInvalidToken     = "invalid_continue"
+	CodeInvalidAggr      = "invalid_aggregation"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeNotSupported     = "not_supported"
+	CodeUnknownKind      = "unknown_kind"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeValidation       = "validation_failed"
+	CodeAfterHook        = "after_hook"
+	CodeBadRequest       = "bad_request"
+	CodeInternal         = "internal"
+)
+
+var codes = []struct {
+	Err  error
+	Code string
+}{
+	{ErrNoSuchObject, CodeNotFound},
+	{ErrObjectExists, CodeAlreadyExists},
+	{ErrConflict, CodeConflict},
+	{ErrInvalidFilter, CodeInvalidFilter},
+	{ErrObjectNil, CodeInvalidObject},
+	{ErrInvalidPath, CodeInvalidPath},
+	{ErrInvalidPatch, CodeInvalidPatch},
+	{ErrInvalidToken, CodeInvalidToken},
+	{ErrInvalidAggr, CodeInvalidAggr},
+	{ErrInvalidMethod, CodeMethodNotAllowed},
+	{ErrNotSupported, CodeNotSupported},
+	{ErrUnknownKind, CodeUnknownKind},
+	{ErrUnauthorized, CodeUnauthorized},
+	{ErrForbidden, CodeForbidden},
+}
+
+// ErrorCode returns the code of the sentinel error wrapped
+// by err, or an empty string for other errors
+func ErrorCode(err error) string {
+	for _, c := range codes {
+		if errors.Is(err, c.Err) {
+			return c.Code
+		}
+	}
+
+	return ""
+}
+
+// ErrorForCode returns the sentinel error of the code, or nil
+func ErrorForCode(code string) error {
+	for _, c := range codes {
+		if c.Code == code {
+			return c.Err
+		}
+	}
+
+	return nil
+}
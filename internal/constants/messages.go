@@ -11,4 +11,12 @@ var (
 	ErrNoSuchObject  = errors.New("object does not exist")
 	ErrInvalidFilter = errors.New("invalid filter key")
 	ErrInvalidPath   = errors.New("invalid request path")
+	ErrInvalidPatch  = errors.New("invalid patch")
+	ErrInvalidToken  = errors.New("invalid continue token")
+	ErrInvalidAggr   = errors.New("invalid aggregation")
+	ErrNotSupported  = errors.New("operation not supported")
+	ErrConflict      = errors.New("object revision conflict")
+	ErrUnknownKind   = errors.New("unknown object kind")
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrForbidden     = errors.New("forbidden")
 )
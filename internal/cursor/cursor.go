@@ -0,0 +1,98 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/store"
+	"github.com/wazofski/gostorz/store/options"
+)
+
+// Cursor is the position of the last object of a page.
+// Listings order by the OrderBy value and then the primary key,
+// the next page starts with the objects past both
+type Cursor struct {
+	Kind    string      `json:"k"`
+	OrderBy string      `json:"o,omitempty"`
+	Desc    bool        `json:"d,omitempty"`
+	Value   interface{} `json:"v,omitempty"`
+	Pkey    string      `json:"p"`
+}
+
+// Descending tells whether the listing runs in descending order,
+// listings without OrderBy always run by ascending primary key
+func Descending(copt options.CommonOptionHolder) bool {
+	return len(copt.OrderBy) > 0 && !copt.OrderIncremental
+}
+
+// Parse decodes the continue token of the options, nil without one.
+// Tokens of other kinds or orders are rejected
+func Parse(kind string, copt options.CommonOptionHolder) (*Cursor, error) {
+	if len(copt.Continue) == 0 {
+		return nil, nil
+	}
+
+	if copt.PageOffset > 0 {
+		return nil, fmt.Errorf("%w: cannot be combined with a page offset",
+			constants.ErrInvalidToken)
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(copt.Continue)
+	if err != nil {
+		return nil, constants.ErrInvalidToken
+	}
+
+	res := &Cursor{}
+	err = json.Unmarshal(data, res)
+	if err != nil {
+		return nil, constants.ErrInvalidToken
+	}
+
+	if res.Kind != kind || len(res.Pkey) == 0 ||
+		res.OrderBy != copt.OrderBy || res.Desc != Descending(copt) {
+		return nil, fmt.Errorf("%w: listing does not match the token",
+			constants.ErrInvalidToken)
+	}
+
+	return res, nil
+}
+
+// Token encodes the cursor as an opaque continue token
+func (c Cursor) Token() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Page cuts the objects listed with one more than the page size,
+// continuing after the last object kept when there are more.
+// The position function returns the OrderBy value the store compares
+// and the primary key, which projected objects may not hold
+func Page(
+	kind string,
+	copt options.CommonOptionHolder,
+	list store.ObjectList,
+	position func(store.Object) (interface{}, string)) *store.Page {
+
+	res := &store.Page{Items: list}
+	if copt.PageSize <= 0 || len(list) <= copt.PageSize {
+		return res
+	}
+
+	res.Items = list[:copt.PageSize]
+	value, pkey := position(res.Items[len(res.Items)-1])
+	next := Cursor{
+		Kind:    kind,
+		OrderBy: copt.OrderBy,
+		Desc:    Descending(copt),
+		Pkey:    pkey,
+	}
+
+	if len(copt.OrderBy) > 0 {
+		next.Value = value
+	}
+
+	res.Continue = next.Token()
+	return res
+}
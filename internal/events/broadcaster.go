@@ -0,0 +1,110 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/wazofski/gostorz/internal/logger"
+	"github.com/wazofski/gostorz/store"
+	"github.com/wazofski/gostorz/store/options"
+)
+
+var log = logger.Factory("events")
+
+// BufferSize is the number of events a watcher can fall behind
+const BufferSize = 256
+
+type Broadcaster struct {
+	lock        sync.Mutex
+	subscribers map[int]*subscriber
+	next        int
+}
+
+// subscriber is a watcher, Done closes along with
+// the channel whichever way it is dropped
+type subscriber struct {
+	Filter  *Filter
+	Channel chan store.Event
+	Done    chan struct{}
+}
+
+func Factory() *Broadcaster {
+	return &Broadcaster{
+		subscribers: make(map[int]*subscriber),
+	}
+}
+
+func (b *Broadcaster) Subscribe(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.WatchOption) (<-chan store.Event, error) {
+
+	filter, err := FilterFactory(identity, opt...)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &subscriber{
+		Filter:  filter,
+		Channel: make(chan store.Event, BufferSize),
+		Done:    make(chan struct{}),
+	}
+
+	b.lock.Lock()
+	id := b.next
+	b.next++
+	b.subscribers[id] = sub
+	b.lock.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			b.unsubscribe(id)
+		case <-sub.Done:
+		}
+	}()
+
+	return sub.Channel, nil
+}
+
+func (b *Broadcaster) Publish(typ store.EventType, obj store.Object) {
+	if obj == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for id, sub := range b.subscribers {
+		if !sub.Filter.Matches(obj) {
+			continue
+		}
+
+		select {
+		case sub.Channel <- store.Event{Type: typ, Object: obj.Clone()}:
+		default:
+			// slow consumers get closed and have to watch again
+			log.Printf("watcher %d fell behind", id)
+			b.drop(id, sub)
+		}
+	}
+}
+
+func (b *Broadcaster) unsubscribe(id int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	sub, ok := b.subscribers[id]
+	if !ok {
+		return
+	}
+
+	b.drop(id, sub)
+}
+
+// drop closes the subscriber, callers must hold the lock
+func (b *Broadcaster) drop(id int, sub *subscriber) {
+	delete(b.subscribers, id)
+	close(sub.Channel)
+	close(sub.Done)
+}
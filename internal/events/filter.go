@@ -0,0 +1,78 @@
+package events
+
+import (
+	"strings"
+
+	"github.com/wazofski/gostorz/store"
+	"github.com/wazofski/gostorz/store/options"
+	"github.com/wazofski/gostorz/utils"
+)
+
+// Filter selects the objects a watch of the identity is after
+type Filter struct {
+	Identity store.ObjectIdentity
+	Options  options.CommonOptionHolder
+}
+
+func FilterFactory(
+	identity store.ObjectIdentity,
+	opt ...options.WatchOption) (*Filter, error) {
+
+	copt := options.CommonOptionHolderFactory()
+	for _, o := range opt {
+		err := o.ApplyFunction()(&copt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Filter{
+		Identity: identity,
+		Options:  copt,
+	}, nil
+}
+
+func (f *Filter) Matches(obj store.Object) bool {
+	kind := strings.ToLower(obj.Metadata().Kind())
+
+	switch {
+	case f.Identity.Type() == "id":
+		if len(f.Identity.Key()) > 0 &&
+			obj.Metadata().Identity().Path() != f.Identity.Path() {
+			return false
+		}
+	case f.Identity.Type() != kind:
+		return false
+	case len(f.Identity.Key()) > 0 && f.Identity.Key() != obj.PrimaryKey():
+		return false
+	}
+
+	if f.Options.KeyFilter != nil {
+		found := false
+		for _, k := range *f.Options.KeyFilter {
+			if k == obj.PrimaryKey() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if f.Options.PropFilter != nil {
+		val := utils.ObjectPath(obj, f.Options.PropFilter.Key)
+		if val == nil || *val != f.Options.PropFilter.Value {
+			return false
+		}
+	}
+
+	if f.Options.Filter != nil {
+		ok, err := f.Options.Filter.Match(obj)
+		if err != nil || !ok {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,22 @@
+package events
+
+import (
+	"github.com/wazofski/gostorz/store"
+)
+
+// Journal holds back events produced inside a transaction
+// until the transaction is committed
+type Journal struct {
+	Events []store.Event
+}
+
+func (j *Journal) Record(typ store.EventType, obj store.Object) {
+	j.Events = append(j.Events, store.Event{Type: typ, Object: obj.Clone()})
+}
+
+func (j *Journal) Replay(b *Broadcaster) {
+	for _, e := range j.Events {
+		b.Publish(e.Type, e.Object)
+	}
+	j.Events = nil
+}
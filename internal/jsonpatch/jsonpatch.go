@@ -0,0 +1,349 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/wazofski/gostorz/internal/constants"
+)
+
+// Merge applies the RFC 7386 merge patch to the JSON document
+func Merge(doc []byte, patch []byte) ([]byte, error) {
+	var target interface{}
+	err := json.Unmarshal(doc, &target)
+	if err != nil {
+		return nil, err
+	}
+
+	var p interface{}
+	err = json.Unmarshal(patch, &p)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", constants.ErrInvalidPatch, err)
+	}
+
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target interface{}, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+
+		t[k] = merge(t[k], v)
+	}
+
+	return t
+}
+
+// Operation of an RFC 6902 JSON patch
+type Operation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from,omitempty"`
+	Value *json.RawMessage `json:"value,omitempty"`
+}
+
+// Decode parses the RFC 6902 JSON patch operations
+func Decode(patch []byte) ([]Operation, error) {
+	ops := []Operation{}
+	err := json.Unmarshal(patch, &ops)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", constants.ErrInvalidPatch, err)
+	}
+
+	return ops, nil
+}
+
+// Apply runs the RFC 6902 JSON patch on the JSON document,
+// a failing test operation reports a conflict
+func Apply(doc []byte, patch []byte) ([]byte, error) {
+	ops, err := Decode(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	var target interface{}
+	err = json.Unmarshal(doc, &target)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range ops {
+		target, err = apply(target, op)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+func apply(doc interface{}, op Operation) (interface{}, error) {
+	value := func() (interface{}, error) {
+		if op.Value == nil {
+			return nil, invalid("%s %s without a value", op.Op, op.Path)
+		}
+
+		var v interface{}
+		err := json.Unmarshal(*op.Value, &v)
+		return v, err
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.Path, v)
+	case "remove":
+		doc, _, err := remove(doc, op.Path)
+		return doc, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		doc, _, err = remove(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.Path, v)
+	case "move":
+		if strings.HasPrefix(op.Path+"/", op.From+"/") && op.Path != op.From {
+			return nil, invalid("cannot move %s into itself", op.From)
+		}
+		doc, v, err := remove(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.Path, v)
+	case "copy":
+		v, err := get(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.Path, clone(v))
+	case "test":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		current, err := get(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, v) {
+			return nil, fmt.Errorf("%w: test %s failed", constants.ErrConflict, op.Path)
+		}
+		return doc, nil
+	}
+
+	return nil, invalid("unknown operation %s", op.Op)
+}
+
+// Pointer splits the RFC 6901 JSON pointer into its tokens
+func Pointer(path string) ([]string, error) {
+	if len(path) == 0 {
+		return []string{}, nil
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		return nil, invalid("pointer %s does not start with /", path)
+	}
+
+	tokens := strings.Split(path[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func get(doc interface{}, path string) (interface{}, error) {
+	tokens, err := Pointer(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range tokens {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			v, ok := node[t]
+			if !ok {
+				return nil, invalid("%s does not exist", path)
+			}
+			doc = v
+		case []interface{}:
+			i, err := index(t, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, invalid("%s does not exist", path)
+		}
+	}
+
+	return doc, nil
+}
+
+// add sets the value at the path, inserting into arrays,
+// and returns the document replaced by the value at the root
+func add(doc interface{}, path string, value interface{}) (interface{}, error) {
+	tokens, err := Pointer(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parent := doc
+	if len(tokens) > 1 {
+		parent, err = get(doc, "/"+escape(tokens[:len(tokens)-1]))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+	case []interface{}:
+		i := len(node)
+		if last != "-" {
+			i, err = index(last, len(node))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		node = append(node, nil)
+		copy(node[i+1:], node[i:])
+		node[i] = value
+		return replaceParent(doc, tokens[:len(tokens)-1], node)
+	default:
+		return nil, invalid("%s has no parent", path)
+	}
+
+	return doc, nil
+}
+
+// remove deletes the value at the path and returns it
+func remove(doc interface{}, path string) (interface{}, interface{}, error) {
+	tokens, err := Pointer(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+
+	parent := doc
+	if len(tokens) > 1 {
+		parent, err = get(doc, "/"+escape(tokens[:len(tokens)-1]))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		v, ok := node[last]
+		if !ok {
+			return nil, nil, invalid("%s does not exist", path)
+		}
+		delete(node, last)
+		return doc, v, nil
+	case []interface{}:
+		i, err := index(last, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		v := node[i]
+		node = append(node[:i:i], node[i+1:]...)
+		doc, err = replaceParent(doc, tokens[:len(tokens)-1], node)
+		return doc, v, err
+	}
+
+	return nil, nil, invalid("%s does not exist", path)
+}
+
+// replaceParent stores the resized array back at the tokens
+func replaceParent(doc interface{}, tokens []string, node []interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return node, nil
+	}
+
+	grand := doc
+	if len(tokens) > 1 {
+		var err error
+		grand, err = get(doc, "/"+escape(tokens[:len(tokens)-1]))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	last := tokens[len(tokens)-1]
+	switch g := grand.(type) {
+	case map[string]interface{}:
+		g[last] = node
+	case []interface{}:
+		i, err := index(last, len(g)-1)
+		if err != nil {
+			return nil, err
+		}
+		g[i] = node
+	}
+
+	return doc, nil
+}
+
+func index(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max || (len(token) > 1 && token[0] == '0') {
+		return 0, invalid("invalid array index %s", token)
+	}
+
+	return i, nil
+}
+
+func escape(tokens []string) string {
+	escaped := make([]string, len(tokens))
+	for i, t := range tokens {
+		escaped[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~", "~0"), "/", "~1")
+	}
+
+	return strings.Join(escaped, "/")
+}
+
+func clone(v interface{}) interface{} {
+	data, _ := json.Marshal(v)
+	var res interface{}
+	json.Unmarshal(data, &res)
+	return res
+}
+
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", constants.ErrInvalidPatch, fmt.Sprintf(format, args...))
+}
@@ -3,6 +3,7 @@ package logger
 import (
 	"context"
 
+	"github.com/wazofski/gostorz/internal/constants"
 	"github.com/wazofski/gostorz/store"
 	"github.com/wazofski/gostorz/store/options"
 )
@@ -52,6 +53,27 @@ func (d *loggerStore) Update(
 	return ret, err
 }
 
+func (d *loggerStore) Patch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	patch store.Patch,
+	opt ...options.UpdateOption) (store.Object, error) {
+
+	patcher, ok := d.Store.(store.Patcher)
+	if !ok {
+		err := constants.ErrNotSupported
+		d.Logger.Printf(err.Error())
+		return nil, err
+	}
+
+	ret, err := patcher.Patch(ctx, identity, patch, opt...)
+	d.Logger.Object("ret", ret)
+	if err != nil {
+		d.Logger.Printf(err.Error())
+	}
+	return ret, err
+}
+
 func (d *loggerStore) Delete(
 	ctx context.Context,
 	identity store.ObjectIdentity,
@@ -89,3 +111,93 @@ func (d *loggerStore) List(
 	}
 	return ret, err
 }
+
+func (d *loggerStore) ListPage(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.ListOption) (*store.Page, error) {
+
+	pager, ok := d.Store.(store.Pager)
+	if !ok {
+		err := constants.ErrNotSupported
+		d.Logger.Printf(err.Error())
+		return nil, err
+	}
+
+	ret, err := pager.ListPage(ctx, identity, opt...)
+	d.Logger.Object("ret", ret)
+	if err != nil {
+		d.Logger.Printf(err.Error())
+	}
+	return ret, err
+}
+
+func (d *loggerStore) Count(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.ListOption) (int, error) {
+
+	ret, err := store.Count(ctx, d.Store, identity, opt...)
+	d.Logger.Object("ret", ret)
+	if err != nil {
+		d.Logger.Printf(err.Error())
+	}
+	return ret, err
+}
+
+func (d *loggerStore) Aggregate(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	agg store.Aggregation,
+	opt ...options.ListOption) ([]store.AggregateResult, error) {
+
+	ret, err := store.AggregateList(ctx, d.Store, identity, agg, opt...)
+	d.Logger.Object("ret", ret)
+	if err != nil {
+		d.Logger.Printf(err.Error())
+	}
+	return ret, err
+}
+
+func (d *loggerStore) Watch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.WatchOption) (<-chan store.Event, error) {
+
+	watcher, ok := d.Store.(store.Watcher)
+	if !ok {
+		err := constants.ErrNotSupported
+		d.Logger.Printf(err.Error())
+		return nil, err
+	}
+
+	ret, err := watcher.Watch(ctx, identity, opt...)
+	if err != nil {
+		d.Logger.Printf(err.Error())
+	}
+	return ret, err
+}
+
+func (d *loggerStore) Txn(
+	ctx context.Context,
+	fn func(store.Store) error) error {
+
+	transactional, ok := d.Store.(store.Transactional)
+	if !ok {
+		err := constants.ErrNotSupported
+		d.Logger.Printf(err.Error())
+		return err
+	}
+
+	err := transactional.Txn(ctx, func(tx store.Store) error {
+		return fn(&loggerStore{
+			Schema: d.Schema,
+			Store:  tx,
+			Logger: d.Logger,
+		})
+	})
+	if err != nil {
+		d.Logger.Printf(err.Error())
+	}
+	return err
+}
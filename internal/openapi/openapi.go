@@ -0,0 +1,376 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/wazofski/gostorz/store"
+)
+
+// Path serves the document
+const Path = "/openapi.json"
+
+// Query arguments of the REST API
+const (
+	PropFilterArg  = "pf"
+	KeyFilterArg   = "kf"
+	FilterArg      = "filter"
+	IncrementalArg = "inc"
+	PageSizeArg    = "pageSize"
+	PageOffsetArg  = "pageOffset"
+	ContinueArg    = "continue"
+	TotalArg       = "total"
+	OrderByArg     = "orderBy"
+	RevisionArg    = "revision"
+	WatchArg       = "watch"
+	AggregateArg   = "aggregate"
+	FieldsArg      = "fields"
+)
+
+// List replies carry the token of the next page
+// and the total count when requested
+const (
+	ContinueHeader = "X-Continue"
+	TotalHeader    = "X-Total-Count"
+)
+
+type Action string
+
+const (
+	ActionCreate Action = http.MethodPost
+	ActionUpdate Action = http.MethodPut
+	ActionDelete Action = http.MethodDelete
+	ActionGet    Action = http.MethodGet
+)
+
+type _Json = map[string]interface{}
+
+// Document builds an OpenAPI 3 document describing the actions exposed per kind.
+// Components are the JSON schemas of the model types keyed by name,
+// as produced by mgen.
+func Document(components map[string]interface{}, exposed map[string][]Action) ([]byte, error) {
+	schemas := _Json{
+		"Meta":  metaSchema(),
+		"Error": errorSchema(),
+	}
+	for k, v := range components {
+		schemas[k] = v
+	}
+
+	paths := _Json{}
+	idItem := _Json{}
+	idKinds := map[Action][]interface{}{}
+
+	kinds := []string{}
+	for k := range exposed {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+
+	for _, kind := range kinds {
+		actions := exposed[kind]
+		if schemas[kind] == nil {
+			schemas[kind] = _Json{"type": "object"}
+		}
+
+		ref := schemaRef(kind)
+		lk := strings.ToLower(kind)
+		typeItem := _Json{}
+		objectItem := _Json{}
+
+		if slices.Contains(actions, ActionGet) {
+			typeItem["get"] = operation("list"+kind,
+				fmt.Sprintf("List %s objects", kind),
+				_Json{"type": "array", "items": ref}, listParameters()...)
+			objectItem["get"] = operation("get"+kind,
+				fmt.Sprintf("Get %s by primary key", kind), ref,
+				fieldsParameter(), ifNoneMatchParameter())
+		}
+
+		if slices.Contains(actions, ActionCreate) {
+			op := operation("create"+kind,
+				fmt.Sprintf("Create %s", kind), ref)
+			op["requestBody"] = requestBody(ref)
+			typeItem["post"] = op
+		}
+
+		if slices.Contains(actions, ActionUpdate) {
+			op := operation("update"+kind,
+				fmt.Sprintf("Update %s by primary key", kind), ref,
+				revisionParameter(), ifMatchParameter())
+			op["requestBody"] = requestBody(ref)
+			objectItem["put"] = op
+
+			op = operation("patch"+kind,
+				fmt.Sprintf("Patch %s by primary key", kind), ref,
+				revisionParameter(), ifMatchParameter())
+			op["requestBody"] = patchBody()
+			objectItem["patch"] = op
+		}
+
+		if slices.Contains(actions, ActionDelete) {
+			objectItem["delete"] = operation("delete"+kind,
+				fmt.Sprintf("Delete %s by primary key", kind), nil,
+				revisionParameter(), ifMatchParameter())
+		}
+
+		for _, a := range actions {
+			idKinds[a] = append(idKinds[a], ref)
+		}
+
+		if len(typeItem) > 0 {
+			paths["/"+lk] = typeItem
+		}
+
+		if len(objectItem) > 0 {
+			objectItem["parameters"] = []interface{}{
+				pathParameter("pkey", primaryKeyDescription(schemas[kind])),
+			}
+			paths[fmt.Sprintf("/%s/{pkey}", lk)] = objectItem
+		}
+	}
+
+	if len(idKinds[ActionGet]) > 0 {
+		idItem["get"] = operation("getById",
+			"Get any exposed object by identity", _Json{"oneOf": idKinds[ActionGet]},
+			fieldsParameter(), ifNoneMatchParameter())
+	}
+
+	if len(idKinds[ActionUpdate]) > 0 {
+		op := operation("updateById",
+			"Update any exposed object by identity", _Json{"oneOf": idKinds[ActionUpdate]},
+			revisionParameter(), ifMatchParameter())
+		op["requestBody"] = requestBody(_Json{"oneOf": idKinds[ActionUpdate]})
+		idItem["put"] = op
+
+		op = operation("patchById",
+			"Patch any exposed object by identity", _Json{"oneOf": idKinds[ActionUpdate]},
+			revisionParameter(), ifMatchParameter())
+		op["requestBody"] = patchBody()
+		idItem["patch"] = op
+	}
+
+	if len(idKinds[ActionDelete]) > 0 {
+		idItem["delete"] = operation("deleteById",
+			"Delete any exposed object by identity", nil,
+			revisionParameter(), ifMatchParameter())
+	}
+
+	if len(idItem) > 0 {
+		idItem["parameters"] = []interface{}{
+			pathParameter("id", "Object identity"),
+		}
+		paths["/id/{id}"] = idItem
+	}
+
+	return json.MarshalIndent(_Json{
+		"openapi": "3.0.3",
+		"info": _Json{
+			"title":   "gostorz",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": _Json{
+			"schemas": schemas,
+		},
+	}, "", "  ")
+}
+
+func schemaRef(name string) _Json {
+	return _Json{"$ref": "#/components/schemas/" + name}
+}
+
+func metaSchema() _Json {
+	return _Json{
+		"type": "object",
+		"properties": _Json{
+			"kind":     _Json{"type": "string"},
+			"identity": _Json{"type": "string"},
+			"created":  _Json{"type": "string"},
+			"updated":  _Json{"type": "string"},
+			"revision": _Json{"type": "integer", "format": "int64"},
+		},
+	}
+}
+
+func errorSchema() _Json {
+	return _Json{
+		"type": "object",
+		"properties": _Json{
+			"error": _Json{
+				"type": "object",
+				"properties": _Json{
+					"code":    _Json{"type": "string"},
+					"message": _Json{"type": "string"},
+					"path":    _Json{"type": "string"},
+				},
+			},
+		},
+	}
+}
+
+func operation(id string, summary string, result _Json, params ..._Json) _Json {
+	ok := _Json{"description": "OK"}
+	if result != nil {
+		ok["content"] = _Json{
+			"application/json": _Json{"schema": result},
+		}
+	}
+	if result != nil && result["type"] != "array" {
+		ok["headers"] = _Json{
+			"ETag": _Json{
+				"description": "Entity tag of the object",
+				"schema":      _Json{"type": "string"},
+			},
+		}
+	} else if result != nil {
+		ok["headers"] = _Json{
+			ContinueHeader: _Json{
+				"description": "Token of the next page, missing on the last page",
+				"schema":      _Json{"type": "string"},
+			},
+			TotalHeader: _Json{
+				"description": "Number of objects matching the filters",
+				"schema":      _Json{"type": "integer"},
+			},
+		}
+	}
+
+	op := _Json{
+		"operationId": id,
+		"summary":     summary,
+		"responses": _Json{
+			"200": ok,
+			"default": _Json{
+				"description": "Error",
+				"content": _Json{
+					"application/json": _Json{"schema": schemaRef("Error")},
+				},
+			},
+		},
+	}
+
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	return op
+}
+
+func requestBody(schema _Json) _Json {
+	return _Json{
+		"required": true,
+		"content": _Json{
+			"application/json": _Json{"schema": schema},
+		},
+	}
+}
+
+// patchBody accepts merge patches and JSON patches of the external properties
+func patchBody() _Json {
+	return _Json{
+		"required": true,
+		"content": _Json{
+			string(store.MergePatchType): _Json{"schema": _Json{"type": "object"}},
+			string(store.JSONPatchType): _Json{"schema": _Json{
+				"type": "array",
+				"items": _Json{
+					"type":     "object",
+					"required": []string{"op", "path"},
+					"properties": _Json{
+						"op": _Json{
+							"type": "string",
+							"enum": []string{"add", "remove", "replace", "move", "copy", "test"},
+						},
+						"path":  _Json{"type": "string"},
+						"from":  _Json{"type": "string"},
+						"value": _Json{},
+					},
+				},
+			}},
+		},
+	}
+}
+
+func pathParameter(name string, description string) _Json {
+	return _Json{
+		"name":        name,
+		"in":          "path",
+		"required":    true,
+		"description": description,
+		"schema":      _Json{"type": "string"},
+	}
+}
+
+func queryParameter(name string, description string, typ string) _Json {
+	return _Json{
+		"name":        name,
+		"in":          "query",
+		"description": description,
+		"schema":      _Json{"type": typ},
+	}
+}
+
+func headerParameter(name string, description string) _Json {
+	return _Json{
+		"name":        name,
+		"in":          "header",
+		"description": description,
+		"schema":      _Json{"type": "string"},
+	}
+}
+
+func revisionParameter() _Json {
+	return queryParameter(RevisionArg,
+		"Fail with 412 unless the stored revision matches", "integer")
+}
+
+func ifMatchParameter() _Json {
+	return headerParameter("If-Match",
+		"Fail with 412 unless the stored object has one of the ETags")
+}
+
+func ifNoneMatchParameter() _Json {
+	return headerParameter("If-None-Match",
+		"Reply 304 Not Modified when the object has one of the ETags")
+}
+
+func fieldsParameter() _Json {
+	return queryParameter(FieldsArg,
+		"Comma separated property paths to reply, the metadata kind and identity are kept", "string")
+}
+
+func listParameters() []_Json {
+	return []_Json{
+		queryParameter(PropFilterArg, `Property filter {"key": ..., "value": ...}`, "string"),
+		queryParameter(KeyFilterArg, "JSON list of primary keys", "string"),
+		queryParameter(FilterArg, "JSON filter expression", "string"),
+		queryParameter(OrderByArg, "Property path to order by", "string"),
+		queryParameter(IncrementalArg, "Order ascending, defaults to true", "boolean"),
+		queryParameter(PageSizeArg, "Page size", "integer"),
+		queryParameter(PageOffsetArg, "Page offset", "integer"),
+		queryParameter(ContinueArg, "Continue token of the previous page", "string"),
+		queryParameter(TotalArg, "Reply the total count, defaults to false", "boolean"),
+		fieldsParameter(),
+		queryParameter(AggregateArg,
+			`JSON aggregation {"function": "count|sum|min|max", "property": ..., "groupBy": ...}, `+
+				`replies [{"group": ..., "count": ..., "value": ...}] instead of the objects`, "string"),
+	}
+}
+
+func primaryKeyDescription(schema interface{}) string {
+	obj, ok := schema.(map[string]interface{})
+	if ok {
+		pkey, ok := obj["x-primary-key"].(string)
+		if ok {
+			return fmt.Sprintf("Primary key (%s)", pkey)
+		}
+	}
+
+	return "Primary key"
+}
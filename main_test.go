@@ -1,6 +1,8 @@
 package main_test
 
 import (
+	"os"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/wazofski/gostorz/mgen"
@@ -10,5 +12,8 @@ var _ = Describe("gostorz", func() {
 	It("mgen can generate", func() {
 		err := mgen.Generate("test/model")
 		Expect(err).To(BeNil())
+
+		_, err = os.Stat("generated/openapi.json")
+		Expect(err).To(BeNil())
 	})
 })
@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 
+	"github.com/wazofski/gostorz/filter"
 	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/internal/cursor"
+	"github.com/wazofski/gostorz/internal/events"
 	"github.com/wazofski/gostorz/internal/logger"
 	"github.com/wazofski/gostorz/store"
 	"github.com/wazofski/gostorz/store/options"
@@ -19,6 +23,9 @@ type memoryStore struct {
 	Schema        store.SchemaHolder
 	IdentityIndex map[string]*store.Object
 	PrimaryIndex  map[string]map[string]*store.Object
+	Events        *events.Broadcaster
+	Journal       *events.Journal
+	Lock          sync.RWMutex
 }
 
 func Factory() store.Factory {
@@ -27,6 +34,7 @@ func Factory() store.Factory {
 			Schema:        schema,
 			IdentityIndex: make(map[string]*store.Object),
 			PrimaryIndex:  make(map[string]map[string]*store.Object),
+			Events:        events.Factory(),
 		}
 
 		return client, nil
@@ -58,15 +66,19 @@ func (d *memoryStore) Create(
 		return nil, constants.ErrObjectNil
 	}
 
+	d.Lock.Lock()
+	defer d.Lock.Unlock()
+
 	lk := strings.ToLower(obj.Metadata().Kind())
 	path := fmt.Sprintf("%s/%s", lk, obj.PrimaryKey())
-	existing, _ := d.Get(ctx, store.ObjectIdentity(path))
+	existing := d.get(store.ObjectIdentity(path))
 
 	if existing != nil {
 		return nil, constants.ErrObjectExists
 	}
 
 	clone := obj.Clone()
+	clone.Metadata().(store.MetaSetter).SetRevision(1)
 	// log.Println(utils.PP(clone))
 
 	// log.Printf("creating %s", obj.Metadata().Identity())
@@ -78,6 +90,7 @@ func (d *memoryStore) Create(
 	}
 
 	d.PrimaryIndex[lk][obj.PrimaryKey()] = &clone
+	d.publish(store.EventCreated, clone)
 
 	return clone.Clone(), nil
 }
@@ -103,12 +116,65 @@ func (d *memoryStore) Update(
 		return nil, constants.ErrObjectNil
 	}
 
-	existing, _ := d.Get(ctx, identity)
+	d.Lock.Lock()
+	defer d.Lock.Unlock()
+
+	existing := d.get(identity)
+	if existing == nil {
+		return nil, constants.ErrNoSuchObject
+	}
+
+	revision := existing.Metadata().Revision()
+	if copt.Revision != nil && *copt.Revision != revision {
+		return nil, constants.ErrConflict
+	}
+
+	return d.update(existing, obj), nil
+}
+
+// Patch applies the patch under the store lock
+func (d *memoryStore) Patch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	patch store.Patch,
+	opt ...options.UpdateOption) (store.Object, error) {
+
+	log.Printf("patch %s", identity.Path())
+
+	var err error
+	copt := options.CommonOptionHolderFactory()
+	for _, o := range opt {
+		err = o.ApplyFunction()(&copt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	d.Lock.Lock()
+	defer d.Lock.Unlock()
+
+	existing := d.get(identity)
 	if existing == nil {
 		return nil, constants.ErrNoSuchObject
 	}
 
+	if copt.Revision != nil && *copt.Revision != existing.Metadata().Revision() {
+		return nil, constants.ErrConflict
+	}
+
+	obj, err := store.ApplyPatch(d.Schema, existing, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.update(existing, obj), nil
+}
+
+// update replaces the existing object,
+// callers must hold the lock
+func (d *memoryStore) update(existing store.Object, obj store.Object) store.Object {
 	clone := obj.Clone()
+	clone.Metadata().(store.MetaSetter).SetRevision(existing.Metadata().Revision() + 1)
 
 	d.IdentityIndex[obj.Metadata().Identity().Path()] = &clone
 	lk := strings.ToLower(existing.Metadata().Kind())
@@ -116,8 +182,9 @@ func (d *memoryStore) Update(
 
 	lk = strings.ToLower(obj.Metadata().Kind())
 	d.PrimaryIndex[lk][obj.PrimaryKey()] = &clone
+	d.publish(store.EventUpdated, clone)
 
-	return clone.Clone(), err
+	return clone.Clone()
 }
 
 func (d *memoryStore) Delete(
@@ -136,14 +203,22 @@ func (d *memoryStore) Delete(
 		}
 	}
 
-	existing, _ := d.Get(ctx, identity)
+	d.Lock.Lock()
+	defer d.Lock.Unlock()
+
+	existing := d.get(identity)
 	if existing == nil {
 		return constants.ErrNoSuchObject
 	}
 
+	if copt.Revision != nil && *copt.Revision != existing.Metadata().Revision() {
+		return constants.ErrConflict
+	}
+
 	d.IdentityIndex[identity.Path()] = nil
 	lk := strings.ToLower(existing.Metadata().Kind())
 	d.PrimaryIndex[lk][existing.PrimaryKey()] = nil
+	d.publish(store.EventDeleted, existing)
 
 	return nil
 }
@@ -164,11 +239,23 @@ func (d *memoryStore) Get(
 		}
 	}
 
-	// log.Printf("...GET identity index size: %d", len(d.IdentityIndex))
+	d.Lock.RLock()
+	defer d.Lock.RUnlock()
+
+	ret := d.get(identity)
+	if ret == nil {
+		return nil, constants.ErrNoSuchObject
+	}
+
+	return store.Project(d.Schema, ret, copt.Fields)
+}
 
+// get looks the identity up in the indexes,
+// callers must hold the lock
+func (d *memoryStore) get(identity store.ObjectIdentity) store.Object {
 	ret := d.IdentityIndex[identity.Path()]
 	if ret != nil {
-		return (*ret).Clone(), nil
+		return (*ret).Clone()
 	}
 
 	tokens := strings.Split(identity.Path(), "/")
@@ -176,15 +263,14 @@ func (d *memoryStore) Get(
 		lk := strings.ToLower(tokens[0])
 		km := d.PrimaryIndex[lk]
 		if km != nil {
-			// log.Printf("...GET type index exists with %d records", len(km))
 			ret = km[tokens[1]]
 			if ret != nil {
-				return (*ret).Clone(), nil
+				return (*ret).Clone()
 			}
 		}
 	}
 
-	return nil, constants.ErrNoSuchObject
+	return nil
 }
 
 func (d *memoryStore) List(
@@ -192,33 +278,137 @@ func (d *memoryStore) List(
 	identity store.ObjectIdentity,
 	opt ...options.ListOption) (store.ObjectList, error) {
 
+	page, err := d.ListPage(ctx, identity, opt...)
+	if err != nil {
+		return nil, err
+	}
+
+	return page.Items, nil
+}
+
+func (d *memoryStore) ListPage(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.ListOption) (*store.Page, error) {
+
 	log.Printf("list %s", identity)
 
-	var err error
 	copt := options.CommonOptionHolderFactory()
 	for _, o := range opt {
-		err = o.ApplyFunction()(&copt)
+		err := o.ApplyFunction()(&copt)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	res := store.ObjectList{}
-	everything := d.PrimaryIndex[identity.Type()]
-	if everything == nil {
-		return res, nil
+	res, err := d.filtered(identity, copt)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(identity.Key()) > 0 {
-		return nil, constants.ErrInvalidPath
+	total := len(res)
+	after, err := cursor.Parse(identity.Type(), copt)
+	if err != nil {
+		return nil, err
+	}
+
+	desc := cursor.Descending(copt)
+	// sort results
+	res = listOrder(res, copt.OrderBy, desc)
+	// continue after the token
+	res = listAfter(res, copt.OrderBy, desc, after)
+	// paginate, with one more to tell if there are more
+	size := copt.PageSize
+	if size > 0 {
+		size++
+	}
+	res = listPagination(res, copt.PageOffset, size)
+
+	page := cursor.Page(identity.Type(), copt, res,
+		func(obj store.Object) (interface{}, string) {
+			return orderValue(obj, copt.OrderBy), obj.PrimaryKey()
+		})
+
+	if copt.TotalCount {
+		page.Total = &total
+	}
+
+	// project once the page is cut
+	page.Items, err = store.ProjectList(d.Schema, identity.Type(), page.Items, copt.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (d *memoryStore) Count(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.ListOption) (int, error) {
+
+	log.Printf("count %s", identity)
+
+	copt := options.CommonOptionHolderFactory()
+	for _, o := range opt {
+		err := o.ApplyFunction()(&copt)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := d.filtered(identity, copt)
+	return len(res), err
+}
+
+func (d *memoryStore) Aggregate(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	agg store.Aggregation,
+	opt ...options.ListOption) ([]store.AggregateResult, error) {
+
+	log.Printf("aggregate %s", identity)
+
+	copt := options.CommonOptionHolderFactory()
+	for _, o := range opt {
+		err := o.ApplyFunction()(&copt)
+		if err != nil {
+			return nil, err
+		}
 	}
 
+	res, err := d.filtered(identity, copt)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.Aggregate(res, agg)
+}
+
+// filtered clones the objects of the kind matching the filters
+func (d *memoryStore) filtered(
+	identity store.ObjectIdentity,
+	copt options.CommonOptionHolder) (store.ObjectList, error) {
+
+	// clone under the read lock, filter outside of it
+	res := store.ObjectList{}
+	d.Lock.RLock()
+	everything := d.PrimaryIndex[identity.Type()]
 	for _, v := range everything {
 		if v == nil {
 			continue
 		}
 		res = append(res, (*v).Clone())
 	}
+	d.Lock.RUnlock()
+
+	if everything == nil {
+		return res, nil
+	}
+
+	if len(identity.Key()) > 0 {
+		return nil, constants.ErrInvalidPath
+	}
 
 	if len(res) > 0 && copt.PropFilter != nil {
 		if utils.ObjectPath(res[0], copt.PropFilter.Key) == nil {
@@ -226,14 +416,88 @@ func (d *memoryStore) List(
 		}
 	}
 
+	if copt.Filter != nil {
+		obj := d.Schema.ObjectForKind(identity.Type())
+		if obj == nil {
+			return nil, constants.ErrNoSuchObject
+		}
+		if !utils.FilterKeysExist(obj, copt.Filter) {
+			return nil, constants.ErrInvalidFilter
+		}
+	}
+
 	// key filter results
 	res = listPkeyFilter(res, copt.KeyFilter)
 	// filter results
 	res = listFilter(res, copt.PropFilter)
-	// sort results
-	res = listOrder(res, copt.OrderBy, copt.OrderIncremental)
-	// paginate
-	return listPagination(res, copt.PageOffset, copt.PageSize), nil
+	// expression filter results
+	return listExpressionFilter(res, copt.Filter)
+}
+
+func (d *memoryStore) Watch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.WatchOption) (<-chan store.Event, error) {
+
+	log.Printf("watch %s", identity.Path())
+
+	return d.Events.Subscribe(ctx, identity, opt...)
+}
+
+func (d *memoryStore) Txn(
+	ctx context.Context,
+	fn func(store.Store) error) error {
+
+	log.Printf("txn")
+
+	d.Lock.Lock()
+	defer d.Lock.Unlock()
+
+	// mutations go to copies of the indexes
+	// which replace the originals on commit
+	tx := &memoryStore{
+		Schema:        d.Schema,
+		IdentityIndex: make(map[string]*store.Object),
+		PrimaryIndex:  make(map[string]map[string]*store.Object),
+		Events:        d.Events,
+		Journal:       &events.Journal{},
+	}
+
+	for k, v := range d.IdentityIndex {
+		tx.IdentityIndex[k] = v
+	}
+
+	for t, m := range d.PrimaryIndex {
+		tx.PrimaryIndex[t] = make(map[string]*store.Object)
+		for k, v := range m {
+			tx.PrimaryIndex[t][k] = v
+		}
+	}
+
+	err := fn(tx)
+	if err != nil {
+		return err
+	}
+
+	d.IdentityIndex = tx.IdentityIndex
+	d.PrimaryIndex = tx.PrimaryIndex
+
+	if d.Journal != nil {
+		d.Journal.Events = append(d.Journal.Events, tx.Journal.Events...)
+	} else {
+		tx.Journal.Replay(d.Events)
+	}
+
+	return nil
+}
+
+func (d *memoryStore) publish(typ store.EventType, obj store.Object) {
+	if d.Journal != nil {
+		d.Journal.Record(typ, obj)
+		return
+	}
+
+	d.Events.Publish(typ, obj)
 }
 
 func listPkeyFilter(list store.ObjectList, filter *options.KeyFilterSetting) store.ObjectList {
@@ -273,21 +537,78 @@ func listFilter(list store.ObjectList, filter *options.PropFilterSetting) store.
 	return res
 }
 
-func listOrder(list store.ObjectList, ob string, inc bool) store.ObjectList {
-	if len(ob) == 0 {
-		return list
+func listExpressionFilter(list store.ObjectList, expr *filter.Expression) (store.ObjectList, error) {
+	if expr == nil {
+		return list, nil
 	}
 
-	sort.Slice(list, func(p, q int) bool {
-		if inc {
-			return *utils.ObjectPath(list[p], ob) < *utils.ObjectPath(list[q], ob)
+	res := store.ObjectList{}
+	for _, o := range list {
+		ok, err := expr.Match(o)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			res = append(res, o)
 		}
-		return *utils.ObjectPath(list[p], ob) > *utils.ObjectPath(list[q], ob)
+	}
+
+	return res, nil
+}
+
+// listOrder sorts by the order value and then the primary key
+func listOrder(list store.ObjectList, ob string, desc bool) store.ObjectList {
+	values := make(map[store.Object]interface{}, len(list))
+	for _, o := range list {
+		values[o] = orderValue(o, ob)
+	}
+
+	sort.Slice(list, func(p, q int) bool {
+		return less(values[list[p]], list[p].PrimaryKey(),
+			values[list[q]], list[q].PrimaryKey(), desc)
 	})
 
 	return list
 }
 
+// listAfter drops the sorted objects up to the cursor
+func listAfter(list store.ObjectList, ob string, desc bool, after *cursor.Cursor) store.ObjectList {
+	if after == nil {
+		return list
+	}
+
+	for i, o := range list {
+		if less(after.Value, after.Pkey, orderValue(o, ob), o.PrimaryKey(), desc) {
+			return list[i:]
+		}
+	}
+
+	return store.ObjectList{}
+}
+
+// less compares the order values the way the filters do,
+// numbers order numerically, and then the primary keys
+func less(pv interface{}, pk string, qv interface{}, qk string, desc bool) bool {
+	res, _ := filter.Compare(pv, qv)
+	if res == 0 {
+		res = strings.Compare(pk, qk)
+	}
+
+	if desc {
+		return res > 0
+	}
+	return res < 0
+}
+
+func orderValue(obj store.Object, ob string) interface{} {
+	if len(ob) == 0 {
+		return nil
+	}
+
+	return utils.ObjectValue(obj, ob)
+}
+
 func listPagination(list store.ObjectList, offset int, size int) store.ObjectList {
 	lr := len(list)
 
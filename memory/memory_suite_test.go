@@ -0,0 +1,22 @@
+package memory_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/memory"
+	"github.com/wazofski/gostorz/store"
+)
+
+func TestMemory(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "memory suite")
+}
+
+var mem store.Store
+
+var _ = BeforeSuite(func() {
+	mem = store.New(generated.Schema(), memory.Factory())
+})
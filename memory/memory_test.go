@@ -0,0 +1,144 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/memory"
+	"github.com/wazofski/gostorz/store"
+)
+
+var _ = Describe("memory", func() {
+
+	ctx := context.Background()
+
+	workers := 16
+	rounds := 10
+
+	It("can CREATE, UPDATE, LIST and DELETE concurrently", func() {
+		errs := make(chan error, workers*rounds*5)
+		wg := sync.WaitGroup{}
+
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				for r := 0; r < rounds; r++ {
+					world := generated.WorldFactory()
+					world.External().SetName(fmt.Sprintf("world-%d-%d", w, r))
+					ret, err := mem.Create(ctx, world)
+					if err != nil {
+						errs <- err
+						continue
+					}
+
+					world = ret.(generated.World)
+					world.External().SetDescription("updated")
+					_, err = mem.Update(ctx, world.Metadata().Identity(), world)
+					if err != nil {
+						errs <- err
+					}
+
+					_, err = mem.Get(ctx, world.Metadata().Identity())
+					if err != nil {
+						errs <- err
+					}
+
+					_, err = mem.List(ctx, generated.WorldKindIdentity())
+					if err != nil {
+						errs <- err
+					}
+
+					if r%2 == 0 {
+						err = mem.Delete(ctx, world.Metadata().Identity())
+						if err != nil {
+							errs <- err
+						}
+					}
+				}
+			}(w)
+		}
+
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			Expect(err).To(BeNil())
+		}
+
+		ret, err := mem.List(ctx, generated.WorldKindIdentity())
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(workers * rounds / 2))
+	})
+
+	It("can UPDATE the same object concurrently", func() {
+		world := generated.SecondWorldFactory()
+		world.External().SetName("shared")
+		ret, err := mem.Create(ctx, world)
+		Expect(err).To(BeNil())
+		id := ret.Metadata().Identity()
+
+		errs := make(chan error, workers*rounds)
+		wg := sync.WaitGroup{}
+
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				for r := 0; r < rounds; r++ {
+					upd := ret.Clone().(generated.SecondWorld)
+					upd.External().SetDescription(fmt.Sprintf("%d-%d", w, r))
+					_, err := mem.Update(ctx, id, upd)
+					if err != nil {
+						errs <- err
+					}
+				}
+			}(w)
+		}
+
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			Expect(err).To(BeNil())
+		}
+
+		ret, err = mem.Get(ctx, id)
+		Expect(err).To(BeNil())
+		Expect(ret.Metadata().Revision()).To(Equal(int64(1 + workers*rounds)))
+	})
+	It("can release watchers dropped for falling behind", func() {
+		watched := store.New(generated.Schema(), memory.Factory())
+		baseline := runtime.NumGoroutine()
+
+		channels := []<-chan store.Event{}
+		for i := 0; i < 10; i++ {
+			events, err := watched.(store.Watcher).Watch(ctx, generated.WorldIdentity(""))
+			Expect(err).To(BeNil())
+			channels = append(channels, events)
+		}
+
+		for i := 0; i < 300; i++ {
+			world := generated.WorldFactory()
+			world.External().SetName(fmt.Sprintf("behind-%d", i))
+			_, err := watched.Create(ctx, world)
+			Expect(err).To(BeNil())
+		}
+
+		for _, events := range channels {
+			for range events {
+			}
+		}
+
+		Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", baseline))
+	})
+})
@@ -13,7 +13,10 @@ import (
 )
 
 func Generate(model string) error {
-	structs, resources := loadModel(model)
+	structs, resources, err := loadModel(model)
+	if err != nil {
+		return err
+	}
 
 	imports := []string{
 		// "errors",
@@ -25,9 +28,20 @@ func Generate(model string) error {
 		"github.com/wazofski/gostorz/store",
 	}
 
+	if hasPatterns(structs) {
+		imports = append(imports, "regexp")
+	}
+
+	components := openAPIComponents(structs, resources)
+	spec, err := compileOpenAPI(components)
+	if err != nil {
+		return err
+	}
+
 	var b strings.Builder
 	b.WriteString(render("templates/imports.gotext", imports))
 	b.WriteString(compileResources(resources))
+	b.WriteString(spec)
 	b.WriteString(compileStructs(structs))
 
 	str := strings.ReplaceAll(b.String(), "&#34;", "\"")
@@ -41,7 +55,17 @@ func Generate(model string) error {
 	targetDir := "generated"
 	os.RemoveAll(targetDir)
 
-	return utils.ExportFile(targetDir, "objects.go", string(res))
+	err = utils.ExportFile(targetDir, "objects.go", string(res))
+	if err != nil {
+		return err
+	}
+
+	doc, err := openAPIDocument(components, resources)
+	if err != nil {
+		return err
+	}
+
+	return utils.ExportFile(targetDir, "openapi.json", string(doc))
 }
 
 type _Interface struct {
@@ -139,7 +163,7 @@ func compileStruct(s _Struct) string {
 		}
 	}
 
-	impl := append(s.Implements, "json.Unmarshaler")
+	impl := append(s.Implements, "json.Unmarshaler", "store.Validator")
 
 	b.WriteString(render("templates/interface.gotext", _Interface{
 		Name:       s.Name,
@@ -149,6 +173,7 @@ func compileStruct(s _Struct) string {
 
 	b.WriteString(render("templates/structure.gotext", s))
 	b.WriteString(render("templates/unmarshall.gotext", s))
+	b.WriteString(compileValidate(s))
 
 	return b.String()
 }
@@ -180,12 +205,8 @@ func addDefaultPropValues(props []_Prop) []_Prop {
 			continue
 		}
 
-		res = append(res, _Prop{
-			Name:    p.Name,
-			Json:    p.Json,
-			Type:    p.Type,
-			Default: typeDefault(p.Type),
-		})
+		p.Default = typeDefault(p.Type)
+		res = append(res, p)
 	}
 
 	return res
@@ -2,7 +2,6 @@ package mgen
 
 import (
 	"fmt"
-	"log"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -17,6 +16,14 @@ type _Prop struct {
 	Type    string `yaml:"type"`
 	Json    string
 	Default string
+
+	Required  bool     `yaml:"required,omitempty"`
+	Min       *float64 `yaml:"min,omitempty"`
+	Max       *float64 `yaml:"max,omitempty"`
+	MinLength *int     `yaml:"minLength,omitempty"`
+	MaxLength *int     `yaml:"maxLength,omitempty"`
+	Pattern   string   `yaml:"pattern,omitempty"`
+	Enum      []string `yaml:"enum,omitempty"`
 }
 
 type _Type struct {
@@ -45,6 +52,7 @@ type _Resource struct {
 	External string
 	Internal string
 	Pkey     string
+	PkeyPath string
 	// ApiMethods []_ApiMethod
 }
 
@@ -52,7 +60,7 @@ func (r _Resource) IdentityPrefix() string {
 	return strings.ToLower(r.Name)
 }
 
-func loadModel(path string) ([]_Struct, []_Resource) {
+func loadModel(path string) ([]_Struct, []_Resource, error) {
 	yamls := yamlFiles(path)
 	structs := []_Struct{}
 	resources := []_Resource{}
@@ -60,7 +68,7 @@ func loadModel(path string) ([]_Struct, []_Resource) {
 	for _, y := range yamls {
 		model, err := readModel(y)
 		if err != nil {
-			log.Fatal(err)
+			return nil, nil, fmt.Errorf("%s: %w", y, err)
 		}
 
 		for _, m := range model.Types {
@@ -76,13 +84,13 @@ func loadModel(path string) ([]_Struct, []_Resource) {
 				if len(m.Pkey) > 0 {
 					pkey = m.Pkey
 				}
-				pkey = makePropCallerString(pkey)
 
 				resources = append(resources, _Resource{
 					Name:     m.Name,
 					External: m.External,
 					Internal: m.Internal,
-					Pkey:     pkey,
+					Pkey:     makePropCallerString(pkey),
+					PkeyPath: pkey,
 					// ApiMethods: m.ApiMethods,
 				})
 				continue
@@ -90,7 +98,7 @@ func loadModel(path string) ([]_Struct, []_Resource) {
 		}
 	}
 
-	return structs, resources
+	return structs, resources, nil
 }
 
 func readModel(path string) (*_Model, error) {
@@ -103,19 +111,19 @@ func readModel(path string) (*_Model, error) {
 		return nil, err
 	}
 
+	err = checkPatterns(&data)
+	if err != nil {
+		return nil, err
+	}
+
 	return &data, nil
 }
 
 func capitalizeProps(l []_Prop) []_Prop {
 	res := []_Prop{}
 	for _, p := range l {
-		res = append(res,
-			_Prop{
-				Name:    capitalize(p.Name),
-				Json:    decapitalize(p.Name),
-				Type:    p.Type,
-				Default: p.Default,
-			})
+		p.Name, p.Json = capitalize(p.Name), decapitalize(p.Name)
+		res = append(res, p)
 	}
 	return res
 }
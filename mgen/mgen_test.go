@@ -2,10 +2,15 @@ package mgen_test
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/mgen"
+	"github.com/wazofski/gostorz/store"
 )
 
 var _ = Describe("mgen", func() {
@@ -114,4 +119,65 @@ var _ = Describe("mgen", func() {
 		Expect(len(newWorld.Internal().List())).To(Equal(2))
 	})
 
+	It("can describe the schema", func() {
+		describer, ok := generated.Schema().(store.Describer)
+		Expect(ok).To(BeTrue())
+
+		components := map[string]interface{}{}
+		err := json.Unmarshal([]byte(describer.OpenAPI()), &components)
+		Expect(err).To(BeNil())
+		Expect(components).To(HaveKey("World"))
+		Expect(components).To(HaveKey("NestedWorld"))
+	})
+
+	It("can validate constraints", func() {
+		world := generated.WorldFactory()
+		Expect(world.Validate()).ToNot(BeNil())
+
+		world.External().SetName("abc")
+		Expect(world.Validate()).To(BeNil())
+
+		world.External().SetName(strings.Repeat("a", 65))
+		Expect(world.Validate()).ToNot(BeNil())
+		world.External().SetName("abc")
+
+		world.External().Nested().SetCounter(-1)
+		Expect(world.Validate()).ToNot(BeNil())
+		world.External().Nested().SetCounter(0)
+
+		world.External().Nested().SetCode("abc")
+		Expect(world.Validate()).ToNot(BeNil())
+		world.External().Nested().SetCode("ABC")
+		Expect(world.Validate()).To(BeNil())
+
+		world.Internal().SetMap(map[string]generated.NestedWorld{
+			"a": generated.NestedWorldFactory(),
+		})
+		world.Internal().Map()["a"].SetColor("purple")
+		err := world.Validate()
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("internal.map.a.color"))
+
+		world.Internal().Map()["a"].SetColor("red")
+		Expect(world.Validate()).To(BeNil())
+	})
+
+	It("can reject invalid patterns", func() {
+		dir := GinkgoT().TempDir()
+		model := `types:
+  - kind: Struct
+    name: Broken
+    properties:
+      - name: code
+        type: string
+        pattern: "^[A-Z"
+`
+		err := os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte(model), 0644)
+		Expect(err).To(BeNil())
+
+		err = mgen.Generate(dir)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("Broken.code"))
+	})
+
 })
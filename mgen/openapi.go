@@ -0,0 +1,153 @@
+package mgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/wazofski/gostorz/internal/openapi"
+)
+
+// openAPIComponents describes the model types as OpenAPI schemas
+func openAPIComponents(structs []_Struct, resources []_Resource) map[string]interface{} {
+	res := map[string]interface{}{}
+
+	for _, s := range structs {
+		props := map[string]interface{}{}
+		required := []string{}
+		for _, p := range s.Props {
+			props[p.Json] = openAPIProperty(p)
+			if p.Required {
+				required = append(required, p.Json)
+			}
+		}
+
+		schema := map[string]interface{}{
+			"type":       "object",
+			"properties": props,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+
+		res[s.Name] = schema
+	}
+
+	for _, r := range resources {
+		props := map[string]interface{}{
+			"metadata": openAPIRef("Meta"),
+		}
+
+		if len(r.External) > 0 {
+			props["external"] = openAPIRef(r.External)
+		}
+
+		if len(r.Internal) > 0 {
+			props["internal"] = openAPIRef(r.Internal)
+		}
+
+		res[r.Name] = map[string]interface{}{
+			"type":          "object",
+			"properties":    props,
+			"x-primary-key": r.PkeyPath,
+		}
+	}
+
+	return res
+}
+
+// openAPIProperty adds the property constraints to its type schema
+func openAPIProperty(p _Prop) map[string]interface{} {
+	res := openAPIType(p.Type)
+	if res["$ref"] != nil {
+		return res
+	}
+
+	minLength, maxLength := "minLength", "maxLength"
+	if p.IsArray() {
+		minLength, maxLength = "minItems", "maxItems"
+	} else if p.IsMap() {
+		minLength, maxLength = "minProperties", "maxProperties"
+	}
+
+	if p.Min != nil {
+		res["minimum"] = *p.Min
+	}
+	if p.Max != nil {
+		res["maximum"] = *p.Max
+	}
+	if p.MinLength != nil {
+		res[minLength] = *p.MinLength
+	}
+	if p.MaxLength != nil {
+		res[maxLength] = *p.MaxLength
+	}
+	if len(p.Pattern) > 0 {
+		res["pattern"] = p.Pattern
+	}
+	if len(p.Enum) > 0 {
+		res["enum"] = p.Enum
+	}
+
+	return res
+}
+
+func openAPIType(tp string) map[string]interface{} {
+	if strings.HasPrefix(tp, "[]") {
+		return map[string]interface{}{
+			"type":  "array",
+			"items": openAPIType(tp[2:]),
+		}
+	}
+
+	if strings.HasPrefix(tp, "map") {
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": openAPIType(tp[strings.LastIndex(tp, "]")+1:]),
+		}
+	}
+
+	switch tp {
+	case "string":
+		return map[string]interface{}{"type": "string"}
+	case "bool":
+		return map[string]interface{}{"type": "boolean"}
+	case "int":
+		return map[string]interface{}{"type": "integer"}
+	case "float":
+		return map[string]interface{}{"type": "number"}
+	default:
+		return openAPIRef(tp)
+	}
+}
+
+func openAPIRef(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"$ref": "#/components/schemas/" + name,
+	}
+}
+
+// compileOpenAPI renders the Schema method returning the components
+func compileOpenAPI(components map[string]interface{}) (string, error) {
+	data, err := json.MarshalIndent(components, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(
+		"\nfunc (o _Schema) OpenAPI() string {\n\treturn `%s`\n}\n",
+		string(data)), nil
+}
+
+// openAPIDocument describes every object with all the actions exposed
+func openAPIDocument(components map[string]interface{}, resources []_Resource) ([]byte, error) {
+	exposed := map[string][]openapi.Action{}
+	for _, r := range resources {
+		exposed[r.Name] = []openapi.Action{
+			openapi.ActionGet, openapi.ActionCreate,
+			openapi.ActionUpdate, openapi.ActionDelete,
+		}
+	}
+
+	return openapi.Document(components, exposed)
+}
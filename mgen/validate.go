@@ -0,0 +1,141 @@
+package mgen
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var primitiveTypes = map[string]bool{
+	"string":     true,
+	"bool":       true,
+	"int":        true,
+	"float":      true,
+	"store.Meta": true,
+}
+
+// compileValidate renders the Validate method checking the property
+// constraints and recursing into nested structures
+func compileValidate(s _Struct) string {
+	var b strings.Builder
+	var patterns strings.Builder
+
+	for _, p := range s.Props {
+		if len(p.Pattern) > 0 {
+			patterns.WriteString(fmt.Sprintf(
+				"var %s = regexp.MustCompile(%s)\n",
+				p.PatternVar(s.Name), strconv.Quote(p.Pattern)))
+		}
+
+		if p.HasConstraints() {
+			b.WriteString(fmt.Sprintf(
+				"\terr = utils.ValidateProperty(%s, *entity.%s_, %s)\n",
+				strconv.Quote(p.Json), p.Name, p.Constraint(s.Name)))
+			b.WriteString("\tif err != nil {\n\t\treturn err\n\t}\n")
+		}
+
+		if primitiveTypes[p.StrippedType()] {
+			continue
+		}
+
+		if p.IsArray() || p.IsMap() {
+			b.WriteString(fmt.Sprintf(
+				"\tfor k, v := range *entity.%s_ {\n", p.Name))
+			b.WriteString(fmt.Sprintf(
+				"\t\terr = utils.ValidateNested(fmt.Sprintf(\"%s.%%v\", k), v.Validate())\n",
+				p.Json))
+			b.WriteString("\t\tif err != nil {\n\t\t\treturn err\n\t\t}\n\t}\n")
+			continue
+		}
+
+		b.WriteString(fmt.Sprintf(
+			"\terr = utils.ValidateNested(%s, entity.%s().Validate())\n",
+			strconv.Quote(p.Json), p.Name))
+		b.WriteString("\tif err != nil {\n\t\treturn err\n\t}\n")
+	}
+
+	checks := b.String()
+	if len(checks) > 0 {
+		checks = "\tvar err error\n" + checks
+	}
+
+	return fmt.Sprintf("\n%s\nfunc (entity *_%s) Validate() error {\n%s\treturn nil\n}\n\n",
+		patterns.String(), s.Name, checks)
+}
+
+func (u _Prop) HasConstraints() bool {
+	return u.Required || u.Min != nil || u.Max != nil ||
+		u.MinLength != nil || u.MaxLength != nil ||
+		len(u.Pattern) > 0 || len(u.Enum) > 0
+}
+
+// PatternVar names the compiled pattern of the property
+func (u _Prop) PatternVar(structName string) string {
+	return fmt.Sprintf("_%s%sPattern", structName, u.Name)
+}
+
+// Constraint renders the utils.Constraint literal of the property
+func (u _Prop) Constraint(structName string) string {
+	fields := []string{}
+
+	if u.Required {
+		fields = append(fields, "Required: true")
+	}
+	if u.Min != nil {
+		fields = append(fields, fmt.Sprintf("Min: utils.Float(%v)", *u.Min))
+	}
+	if u.Max != nil {
+		fields = append(fields, fmt.Sprintf("Max: utils.Float(%v)", *u.Max))
+	}
+	if u.MinLength != nil {
+		fields = append(fields, fmt.Sprintf("MinLength: utils.Int(%d)", *u.MinLength))
+	}
+	if u.MaxLength != nil {
+		fields = append(fields, fmt.Sprintf("MaxLength: utils.Int(%d)", *u.MaxLength))
+	}
+	if len(u.Pattern) > 0 {
+		fields = append(fields, fmt.Sprintf("Pattern: %s", u.PatternVar(structName)))
+	}
+	if len(u.Enum) > 0 {
+		enum := []string{}
+		for _, e := range u.Enum {
+			enum = append(enum, strconv.Quote(e))
+		}
+		fields = append(fields, fmt.Sprintf("Enum: []string{%s}", strings.Join(enum, ", ")))
+	}
+
+	return fmt.Sprintf("utils.Constraint{%s}", strings.Join(fields, ", "))
+}
+
+// hasPatterns tells if any property of the structures declares a pattern
+func hasPatterns(structs []_Struct) bool {
+	for _, s := range structs {
+		for _, p := range s.Props {
+			if len(p.Pattern) > 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// checkPatterns compiles the property patterns of the model,
+// rejecting the ones that are not valid regular expressions
+func checkPatterns(model *_Model) error {
+	for _, t := range model.Types {
+		for _, p := range t.Props {
+			if len(p.Pattern) == 0 {
+				continue
+			}
+
+			_, err := regexp.Compile(p.Pattern)
+			if err != nil {
+				return fmt.Errorf("%s.%s pattern: %w", t.Name, p.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
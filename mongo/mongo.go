@@ -3,11 +3,14 @@ package mongo
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/internal/cursor"
+	"github.com/wazofski/gostorz/internal/events"
 	"github.com/wazofski/gostorz/internal/logger"
 	"github.com/wazofski/gostorz/store"
 	"github.com/wazofski/gostorz/store/options"
@@ -22,11 +25,28 @@ var log = logger.Factory("mongo")
 const collectionName = "objects"
 const timeout = 10 * time.Second
 
+// changeStreamsUnsupported is the error of standalone servers
+const changeStreamsUnsupported = 40573
+
 type mongoStore struct {
-	Schema store.SchemaHolder
-	Client *mongo.Client
-	Path   string
-	DB     string
+	Schema  store.SchemaHolder
+	Client  *mongo.Client
+	Path    string
+	DB      string
+	Session mongo.Session
+}
+
+type _Change struct {
+	OperationType            string `bson:"operationType"`
+	FullDocument             bson.M `bson:"fullDocument"`
+	FullDocumentBeforeChange bson.M `bson:"fullDocumentBeforeChange"`
+}
+
+var changeEvents = map[string]store.EventType{
+	"insert":  store.EventCreated,
+	"replace": store.EventUpdated,
+	"update":  store.EventUpdated,
+	"delete":  store.EventDeleted,
 }
 
 type _Record struct {
@@ -38,6 +58,10 @@ type _Record struct {
 }
 
 func (d *mongoStore) TestConnection() error {
+	if d.Session != nil {
+		return nil
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
@@ -94,9 +118,49 @@ func (d *mongoStore) prepare() error {
 		collection.Indexes().CreateOne(context.Background(), i)
 	}
 
+	// deleted objects are watched through their pre-images,
+	// servers before MongoDB 6.0 do not keep them
+	d.Client.Database(d.DB).RunCommand(context.Background(), bson.D{
+		{Key: "collMod", Value: collectionName},
+		{Key: "changeStreamPreAndPostImages", Value: bson.M{"enabled": true}},
+	})
+
 	return nil
 }
 
+// replicated checks the server runs as a replica set member or
+// behind mongos, standalone servers do not support transactions
+func (d *mongoStore) replicated(ctx context.Context) error {
+	res := struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}{}
+
+	err := d.Client.Database("admin").RunCommand(ctx,
+		bson.D{{Key: "isMaster", Value: 1}}).Decode(&res)
+	if err != nil {
+		return err
+	}
+
+	if len(res.SetName) == 0 && res.Msg != "isdbgrid" {
+		return fmt.Errorf("%w: transactions need a replica set",
+			constants.ErrNotSupported)
+	}
+
+	return nil
+}
+
+// mongoError maps the errors of servers
+// lacking a feature to ErrNotSupported
+func mongoError(err error) error {
+	var serr mongo.ServerError
+	if errors.As(err, &serr) && serr.HasErrorCode(changeStreamsUnsupported) {
+		return fmt.Errorf("%w: %s", constants.ErrNotSupported, err)
+	}
+
+	return err
+}
+
 func Factory(path string, db string) store.Factory {
 	return func(schema store.SchemaHolder) (store.Store, error) {
 		client := &mongoStore{
@@ -126,6 +190,7 @@ func (d *mongoStore) Create(
 	}
 
 	log.Printf("create %s", obj.PrimaryKey())
+	ctx = d.sessionContext(ctx)
 
 	var err error
 	copt := options.CommonOptionHolderFactory()
@@ -148,23 +213,16 @@ func (d *mongoStore) Create(
 		return nil, err
 	}
 
-	typ := strings.ToLower(obj.Metadata().Kind())
+	clone := obj.Clone()
+	clone.Metadata().(store.MetaSetter).SetRevision(1)
 
 	collection := d.Client.Database(d.DB).Collection(collectionName)
-	_, err = collection.InsertOne(ctx,
-		_Record{
-			IdPath: obj.Metadata().Identity().Path(),
-			PkPath: fmt.Sprintf("%s/%s", typ, obj.PrimaryKey()),
-			Pkey:   obj.PrimaryKey(),
-			Type:   typ,
-			Obj:    toBSON(obj),
-		})
-
+	_, err = collection.InsertOne(ctx, makeRecord(clone))
 	if err != nil {
 		return nil, err
 	}
 
-	return obj.Clone(), nil
+	return clone.Clone(), nil
 }
 
 func (d *mongoStore) Update(
@@ -174,6 +232,7 @@ func (d *mongoStore) Update(
 	opt ...options.UpdateOption) (store.Object, error) {
 
 	log.Printf("update %s", identity.Path())
+	ctx = d.sessionContext(ctx)
 
 	var err error
 	copt := options.CommonOptionHolderFactory()
@@ -188,17 +247,49 @@ func (d *mongoStore) Update(
 		return nil, constants.ErrObjectNil
 	}
 
+	existing, _ := d.Get(ctx, identity)
+	if existing == nil {
+		return nil, constants.ErrNoSuchObject
+	}
+
 	err = d.TestConnection()
 	if err != nil {
 		return nil, err
 	}
 
-	err = d.Delete(ctx, identity)
+	revision := existing.Metadata().Revision()
+	if copt.Revision != nil && *copt.Revision != revision {
+		return nil, constants.ErrConflict
+	}
+
+	clone := obj.Clone()
+	clone.Metadata().(store.MetaSetter).SetRevision(revision + 1)
+
+	collection := d.Client.Database(d.DB).Collection(collectionName)
+	res, err := collection.ReplaceOne(ctx,
+		revisionFilter(existing), makeRecord(clone))
 	if err != nil {
 		return nil, err
 	}
 
-	return d.Create(ctx, obj)
+	if res.MatchedCount == 0 {
+		return nil, constants.ErrConflict
+	}
+
+	return clone.Clone(), nil
+}
+
+// Patch applies the patch with revision guarded updates,
+// retrying when other writes get in between
+func (d *mongoStore) Patch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	patch store.Patch,
+	opt ...options.UpdateOption) (store.Object, error) {
+
+	log.Printf("patch %s", identity.Path())
+
+	return store.PatchUpdate(ctx, d.Schema, d, identity, patch, opt...)
 }
 
 func (d *mongoStore) Delete(
@@ -207,6 +298,7 @@ func (d *mongoStore) Delete(
 	opt ...options.DeleteOption) error {
 
 	log.Printf("delete %s", identity.Path())
+	ctx = d.sessionContext(ctx)
 
 	var err error
 	copt := options.CommonOptionHolderFactory()
@@ -227,22 +319,21 @@ func (d *mongoStore) Delete(
 		return err
 	}
 
-	collection := d.Client.Database(d.DB).Collection(collectionName)
-	_, err = collection.DeleteOne(ctx,
-		bson.M{
-			"idpath": identity.Path(),
-		})
+	if copt.Revision != nil && *copt.Revision != existing.Metadata().Revision() {
+		return constants.ErrConflict
+	}
 
+	collection := d.Client.Database(d.DB).Collection(collectionName)
+	res, err := collection.DeleteOne(ctx, revisionFilter(existing))
 	if err != nil {
-		log.Printf(err.Error())
+		return err
 	}
 
-	_, err = collection.DeleteOne(ctx,
-		bson.M{
-			"pkpath": identity.Path(),
-		})
+	if res.DeletedCount == 0 {
+		return constants.ErrConflict
+	}
 
-	return err
+	return nil
 }
 
 func (d *mongoStore) Get(
@@ -251,6 +342,7 @@ func (d *mongoStore) Get(
 	opt ...options.GetOption) (store.Object, error) {
 
 	log.Printf("get %s", identity.Path())
+	ctx = d.sessionContext(ctx)
 
 	var err error
 	copt := options.CommonOptionHolderFactory()
@@ -266,24 +358,33 @@ func (d *mongoStore) Get(
 		return nil, err
 	}
 
+	opts := mopt.FindOne()
+	if len(copt.Fields) > 0 {
+		projection, err := d.projection(identity.Type(), copt.Fields)
+		if err != nil {
+			return nil, err
+		}
+		opts = opts.SetProjection(projection)
+	}
+
 	collection := d.Client.Database(d.DB).Collection(collectionName)
 	var res bson.M
 	collection.FindOne(ctx,
 		bson.M{
 			"idpath": identity.Path(),
-		}).Decode(&res)
+		}, opts).Decode(&res)
 
 	if res != nil {
-		return fromBSON(res, d.Schema)
+		return d.fromProjection(res, copt.Fields)
 	}
 
 	collection.FindOne(ctx,
 		bson.M{
 			"pkpath": identity.Path(),
-		}).Decode(&res)
+		}, opts).Decode(&res)
 
 	if res != nil {
-		return fromBSON(res, d.Schema)
+		return d.fromProjection(res, copt.Fields)
 	}
 
 	return nil, constants.ErrNoSuchObject
@@ -294,7 +395,21 @@ func (d *mongoStore) List(
 	identity store.ObjectIdentity,
 	opt ...options.ListOption) (store.ObjectList, error) {
 
+	page, err := d.ListPage(ctx, identity, opt...)
+	if err != nil {
+		return nil, err
+	}
+
+	return page.Items, nil
+}
+
+func (d *mongoStore) ListPage(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.ListOption) (*store.Page, error) {
+
 	log.Printf("list %s", identity)
+	ctx = d.sessionContext(ctx)
 
 	if len(identity.Key()) > 0 {
 		return nil, constants.ErrInvalidPath
@@ -315,19 +430,145 @@ func (d *mongoStore) List(
 	}
 
 	collection := d.Client.Database(d.DB).Collection(collectionName)
-	filter := bson.M{
-		"type": identity.Type(),
+	filter, err := d.listFilter(identity, copt)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := cursor.Parse(identity.Type(), copt)
+	if err != nil {
+		return nil, err
 	}
 
-	opts := mopt.Find()
+	// order by the property and then the primary key
+	order := 1
+	if cursor.Descending(copt) {
+		order = -1
+	}
+
+	sort := bson.D{}
 	if len(copt.OrderBy) > 0 {
-		order := 1
-		if !copt.OrderIncremental {
-			order = -1
+		sort = append(sort,
+			bson.E{Key: fmt.Sprintf("object.%s", copt.OrderBy), Value: order})
+	}
+
+	opts := mopt.Find().SetSort(append(sort, bson.E{Key: "pkey", Value: order}))
+
+	// projections keep the OrderBy value to continue from
+	if len(copt.Fields) > 0 {
+		fields := copt.Fields
+		if len(copt.OrderBy) > 0 {
+			fields = append(append([]string{}, fields...), copt.OrderBy)
 		}
 
-		opts = opts.SetSort(
-			bson.D{{Key: fmt.Sprintf("object.%s", copt.OrderBy), Value: order}})
+		projection, err := d.projection(identity.Type(), fields)
+		if err != nil {
+			return nil, err
+		}
+		opts = opts.SetProjection(projection)
+	}
+
+	var total *int
+	if copt.TotalCount {
+		count, err := collection.CountDocuments(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		total = new(int)
+		*total = int(count)
+	}
+
+	// continue past the cursor
+	if after != nil {
+		cmp := "$gt"
+		if after.Desc {
+			cmp = "$lt"
+		}
+
+		keyset := bson.M{"pkey": bson.M{cmp: after.Pkey}}
+		if len(copt.OrderBy) > 0 {
+			key := fmt.Sprintf("object.%s", copt.OrderBy)
+			keyset = bson.M{"$or": bson.A{
+				bson.M{key: bson.M{cmp: after.Value}},
+				bson.M{key: after.Value, "pkey": bson.M{cmp: after.Pkey}},
+			}}
+		}
+
+		and, _ := filter["$and"].(bson.A)
+		filter["$and"] = append(and, keyset)
+	}
+
+	// one more to tell if there are more
+	if copt.PageSize > 0 {
+		opts = opts.SetLimit(int64(copt.PageSize + 1))
+	}
+
+	if copt.PageOffset > 0 {
+		opts = opts.SetSkip(int64(copt.PageOffset))
+	}
+
+	cur, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var qres []bson.M
+	if err = cur.All(ctx, &qres); err != nil {
+		return nil, err
+	}
+
+	res := store.ObjectList{}
+	pkeys := make(map[store.Object]string)
+	for _, r := range qres {
+		d, err := fromBSON(r, d.Schema)
+		if err != nil {
+			log.Printf(err.Error())
+			continue
+		}
+		res = append(res, d)
+		pkeys[d], _ = r["pkey"].(string)
+	}
+
+	page := cursor.Page(identity.Type(), copt, res,
+		func(obj store.Object) (interface{}, string) {
+			return utils.ObjectValue(obj, copt.OrderBy), pkeys[obj]
+		})
+	page.Total = total
+
+	// drop the OrderBy value read for the cursor
+	page.Items, err = store.ProjectList(d.Schema, identity.Type(), page.Items, copt.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// projection reads the record fields the cursor needs and the object
+// trimmed to the fields, checked against the schema when the kind is known
+func (d *mongoStore) projection(typ string, fields []string) (bson.M, error) {
+	obj := d.Schema.ObjectForKind(typ)
+
+	res := bson.M{"pkey": 1}
+	for _, f := range store.ProjectionPaths(fields) {
+		if obj != nil && utils.ObjectPath(obj, f) == nil {
+			return nil, constants.ErrInvalidFilter
+		}
+		res["object."+f] = 1
+	}
+
+	return res, nil
+}
+
+// listFilter matches the objects of the kind by the List filters
+func (d *mongoStore) listFilter(
+	identity store.ObjectIdentity,
+	copt options.CommonOptionHolder) (bson.M, error) {
+
+	filter := bson.M{
+		"type": identity.Type(),
 	}
 
 	// pkey filter
@@ -354,15 +595,128 @@ func (d *mongoStore) List(
 		filter[fmt.Sprintf("object.%s", copt.PropFilter.Key)] = copt.PropFilter.Value
 	}
 
-	if copt.PageSize > 0 {
-		opts = opts.SetLimit(int64(copt.PageSize))
+	// expression filter
+	if copt.Filter != nil {
+		obj := d.Schema.ObjectForKind(identity.Type())
+		if obj == nil {
+			return nil, constants.ErrNoSuchObject
+		}
+		if !utils.FilterKeysExist(obj, copt.Filter) {
+			return nil, constants.ErrInvalidFilter
+		}
+
+		filter["$and"] = bson.A{copt.Filter.BSON("object.")}
 	}
 
-	if copt.PageOffset > 0 {
-		opts = opts.SetSkip(int64(copt.PageOffset))
+	return filter, nil
+}
+
+func (d *mongoStore) Count(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.ListOption) (int, error) {
+
+	log.Printf("count %s", identity)
+	ctx = d.sessionContext(ctx)
+
+	if len(identity.Key()) > 0 {
+		return 0, constants.ErrInvalidPath
 	}
 
-	cur, err := collection.Find(ctx, filter, opts)
+	copt := options.CommonOptionHolderFactory()
+	for _, o := range opt {
+		err := o.ApplyFunction()(&copt)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err := d.TestConnection()
+	if err != nil {
+		return 0, err
+	}
+
+	filter, err := d.listFilter(identity, copt)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := d.Client.Database(d.DB).Collection(collectionName).
+		CountDocuments(ctx, filter)
+	return int(count), err
+}
+
+// Aggregate runs a $group pipeline over the matching objects
+func (d *mongoStore) Aggregate(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	agg store.Aggregation,
+	opt ...options.ListOption) ([]store.AggregateResult, error) {
+
+	log.Printf("aggregate %s", identity)
+	ctx = d.sessionContext(ctx)
+
+	if len(identity.Key()) > 0 {
+		return nil, constants.ErrInvalidPath
+	}
+
+	err := agg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	copt := options.CommonOptionHolderFactory()
+	for _, o := range opt {
+		err := o.ApplyFunction()(&copt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = d.TestConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := d.listFilter(identity, copt)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := d.Schema.ObjectForKind(identity.Type())
+	if obj == nil {
+		return nil, constants.ErrNoSuchObject
+	}
+
+	group := bson.M{
+		"_id":   nil,
+		"count": bson.M{"$sum": 1},
+	}
+
+	if len(agg.GroupBy) > 0 {
+		if utils.ObjectPath(obj, agg.GroupBy) == nil {
+			return nil, constants.ErrInvalidFilter
+		}
+		group["_id"] = fmt.Sprintf("$object.%s", agg.GroupBy)
+	}
+
+	if agg.Function != store.AggregateCount {
+		if utils.ObjectPath(obj, agg.Property) == nil {
+			return nil, constants.ErrInvalidFilter
+		}
+		group["value"] = bson.M{
+			fmt.Sprintf("$%s", agg.Function): fmt.Sprintf("$object.%s", agg.Property),
+		}
+	}
+
+	pipeline := bson.A{
+		bson.M{"$match": filter},
+		bson.M{"$group": group},
+		bson.M{"$sort": bson.M{"_id": 1}},
+	}
+
+	cur, err := d.Client.Database(d.DB).Collection(collectionName).
+		Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
@@ -373,17 +727,212 @@ func (d *mongoStore) List(
 		return nil, err
 	}
 
-	res := store.ObjectList{}
+	res := []store.AggregateResult{}
 	for _, r := range qres {
-		d, err := fromBSON(r, d.Schema)
+		res = append(res, store.AggregateResult{
+			Group: bsonValue(r["_id"]),
+			Count: int(bsonValue(r["count"]).(float64)),
+			Value: bsonValue(r["value"]),
+		})
+	}
+
+	// a group of no objects like the other stores
+	if len(res) == 0 && len(agg.GroupBy) == 0 {
+		res = append(res, store.AggregateResult{})
+	}
+
+	return res, nil
+}
+
+// bsonValue converts the numbers of the aggregation to float64
+func bsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+
+	return value
+}
+
+// Watch follows a change stream of the collection, seeing the writes
+// of every process once committed. Change streams need a replica set
+// and deleted objects are read from the pre-images of MongoDB 6.0
+func (d *mongoStore) Watch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.WatchOption) (<-chan store.Event, error) {
+
+	log.Printf("watch %s", identity.Path())
+
+	filter, err := events.FilterFactory(identity, opt...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.TestConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	collection := d.Client.Database(d.DB).Collection(collectionName)
+	stream, err := collection.Watch(ctx, mongo.Pipeline{},
+		mopt.ChangeStream().SetFullDocumentBeforeChange(mopt.WhenAvailable))
+	if err != nil {
+		return nil, mongoError(err)
+	}
+
+	res := make(chan store.Event, events.BufferSize)
+	go d.follow(ctx, stream, filter, res)
+
+	return res, nil
+}
+
+// follow sends the matching changes of the stream
+// until the context is cancelled or the stream fails
+func (d *mongoStore) follow(
+	ctx context.Context,
+	stream *mongo.ChangeStream,
+	filter *events.Filter,
+	res chan<- store.Event) {
+
+	defer close(res)
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		change := _Change{}
+		err := stream.Decode(&change)
 		if err != nil {
-			log.Printf(err.Error())
+			log.Printf("watch %s", err)
+			return
+		}
+
+		ev, ok := d.event(change)
+		if !ok || !filter.Matches(ev.Object) {
 			continue
 		}
-		res = append(res, d)
+
+		select {
+		case res <- ev:
+		case <-ctx.Done():
+			return
+		}
 	}
 
-	return res, nil
+	if stream.Err() != nil && ctx.Err() == nil {
+		log.Printf("watch %s", stream.Err())
+	}
+}
+
+// event converts the change of a record to the event of its object
+func (d *mongoStore) event(change _Change) (store.Event, bool) {
+	typ, ok := changeEvents[change.OperationType]
+	if !ok {
+		return store.Event{}, false
+	}
+
+	record := change.FullDocument
+	if typ == store.EventDeleted {
+		record = change.FullDocumentBeforeChange
+	}
+
+	if record == nil {
+		log.Printf("watch %s without the document", change.OperationType)
+		return store.Event{}, false
+	}
+
+	obj, err := fromBSON(record, d.Schema)
+	if err != nil {
+		log.Printf("watch %s", err)
+		return store.Event{}, false
+	}
+
+	return store.Event{Type: typ, Object: obj}, true
+}
+
+// Txn runs the function in a session transaction, transactions
+// need a replica set, standalone servers fail with ErrNotSupported
+func (d *mongoStore) Txn(
+	ctx context.Context,
+	fn func(store.Store) error) error {
+
+	log.Printf("txn")
+
+	// mongo does not support nested transactions
+	if d.Session != nil {
+		return fn(d)
+	}
+
+	err := d.TestConnection()
+	if err != nil {
+		return err
+	}
+
+	err = d.replicated(ctx)
+	if err != nil {
+		return err
+	}
+
+	session, err := d.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	err = session.StartTransaction()
+	if err != nil {
+		return err
+	}
+
+	client := &mongoStore{
+		Schema:  d.Schema,
+		Client:  d.Client,
+		Path:    d.Path,
+		DB:      d.DB,
+		Session: session,
+	}
+
+	err = fn(client)
+	if err != nil {
+		session.AbortTransaction(ctx)
+		return err
+	}
+
+	return session.CommitTransaction(ctx)
+}
+
+func (d *mongoStore) sessionContext(ctx context.Context) context.Context {
+	if d.Session == nil {
+		return ctx
+	}
+
+	return mongo.NewSessionContext(ctx, d.Session)
+}
+
+func makeRecord(obj store.Object) _Record {
+	typ := strings.ToLower(obj.Metadata().Kind())
+
+	return _Record{
+		IdPath: obj.Metadata().Identity().Path(),
+		PkPath: fmt.Sprintf("%s/%s", typ, obj.PrimaryKey()),
+		Pkey:   obj.PrimaryKey(),
+		Type:   typ,
+		Obj:    toBSON(obj),
+	}
+}
+
+// revisionFilter matches the stored record only while it is unchanged
+func revisionFilter(existing store.Object) bson.M {
+	var revision interface{} = existing.Metadata().Revision()
+	if existing.Metadata().Revision() == 0 {
+		revision = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	return bson.M{
+		"idpath":                   existing.Metadata().Identity().Path(),
+		"object.metadata.revision": revision,
+	}
 }
 
 func toBSON(obj store.Object) interface{} {
@@ -393,6 +942,17 @@ func toBSON(obj store.Object) interface{} {
 	return res
 }
 
+// fromProjection checks the fields of id identities, unknown
+// to the projection, against the kind of the object read
+func (d *mongoStore) fromProjection(m bson.M, fields []string) (store.Object, error) {
+	obj, err := fromBSON(m, d.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.Project(d.Schema, obj, fields)
+}
+
 func fromBSON(m bson.M, schema store.SchemaHolder) (store.Object, error) {
 	if m == nil {
 		return nil, fmt.Errorf("invalid bson")
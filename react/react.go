@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 
 	"github.com/wazofski/gostorz/internal/constants"
 	"github.com/wazofski/gostorz/internal/logger"
@@ -14,27 +15,76 @@ import (
 type Action int
 type Callback func(store.Object, store.Store) error
 
+// UpdateCallback receives the existing and the incoming
+// or committed object of an update
+type UpdateCallback func(old store.Object, new store.Object, st store.Store) error
+
 const (
 	ActionCreate Action = 1
 	ActionUpdate Action = 2
 	ActionDelete Action = 3
+	ActionGet    Action = 4
+	ActionList   Action = 5
+	// ActionAll subscribes to every action
+	ActionAll Action = 6
+)
+
+// AllKinds subscribes to the actions of every kind
+const AllKinds = "*"
+
+// AfterHookError reports an after-hook failing once the write
+// it follows is committed, Create and Update return the committed
+// object along with it
+type AfterHookError struct {
+	Err error
+}
+
+func (e *AfterHookError) Error() string {
+	return fmt.Sprintf("after hook: %s", e.Err)
+}
+
+func (e *AfterHookError) Unwrap() error {
+	return e.Err
+}
+
+type _Phase int
+
+const (
+	phaseCallback _Phase = 0
+	phaseBefore   _Phase = 1
+	phaseAfter    _Phase = 2
 )
 
 type reactStore struct {
-	Schema           store.SchemaHolder
-	Store            store.Store
-	Log              logger.Logger
-	CallbackRegistry map[string]map[Action]Callback
+	Schema   store.SchemaHolder
+	Store    store.Store
+	Log      logger.Logger
+	Registry map[string][]_Register
 }
 
 type _Register struct {
 	Kind     string
 	Action   Action
 	Callback Callback
+	Phase    _Phase
+	Hook     UpdateCallback
+	Priority int
+	Sequence int
 }
 
+// WithPriority orders the callbacks of the same kind and action,
+// lower priorities run first and equal ones in registration order
+func (r _Register) WithPriority(priority int) _Register {
+	r.Priority = priority
+	return r
+}
+
+// Subscribe attaches a callback to the action of a kind.
+// Create callbacks receive the incoming object once validated,
+// Update and Delete callbacks the existing object and Get and List
+// callbacks every object read, which they can shape or reject
 func Subscribe(typ string, action Action, callback Callback) _Register {
-	if action < 1 || action > 3 {
+	if action < ActionCreate || action > ActionAll {
 		log.Fatalf("invalid action %d", action)
 	}
 
@@ -45,32 +95,80 @@ func Subscribe(typ string, action Action, callback Callback) _Register {
 	}
 }
 
+// BeforeCreate hooks run before validation and can
+// mutate the incoming object or veto it by returning an error
+func BeforeCreate(typ string, callback Callback) _Register {
+	return hook(typ, phaseBefore, ActionCreate,
+		func(old store.Object, new store.Object, st store.Store) error {
+			return callback(new, st)
+		})
+}
+
+// BeforeUpdate hooks run before validation and can
+// mutate the incoming object or veto it by returning an error
+func BeforeUpdate(typ string, callback UpdateCallback) _Register {
+	return hook(typ, phaseBefore, ActionUpdate, callback)
+}
+
+// BeforeDelete hooks receive the existing object and can veto the delete
+func BeforeDelete(typ string, callback Callback) _Register {
+	return hook(typ, phaseBefore, ActionDelete,
+		func(old store.Object, new store.Object, st store.Store) error {
+			return callback(old, st)
+		})
+}
+
+// AfterCreate hooks receive the committed object
+func AfterCreate(typ string, callback Callback) _Register {
+	return hook(typ, phaseAfter, ActionCreate,
+		func(old store.Object, new store.Object, st store.Store) error {
+			return callback(new, st)
+		})
+}
+
+// AfterUpdate hooks receive the previous and the committed object
+func AfterUpdate(typ string, callback UpdateCallback) _Register {
+	return hook(typ, phaseAfter, ActionUpdate, callback)
+}
+
+// AfterDelete hooks receive the deleted object
+func AfterDelete(typ string, callback Callback) _Register {
+	return hook(typ, phaseAfter, ActionDelete,
+		func(old store.Object, new store.Object, st store.Store) error {
+			return callback(old, st)
+		})
+}
+
+func hook(typ string, phase _Phase, action Action, callback UpdateCallback) _Register {
+	return _Register{
+		Kind:   typ,
+		Action: action,
+		Phase:  phase,
+		Hook:   callback,
+	}
+}
+
 func ReactFactory(data store.Store, callbacks ..._Register) store.Factory {
 	return func(schema store.SchemaHolder) (store.Store, error) {
 		client := &reactStore{
-			Schema:           schema,
-			Store:            data,
-			Log:              logger.Factory("react"),
-			CallbackRegistry: make(map[string]map[Action]Callback),
+			Schema:   schema,
+			Store:    data,
+			Log:      logger.Factory("react"),
+			Registry: make(map[string][]_Register),
 		}
 
-		for _, c := range callbacks {
-			proto := schema.ObjectForKind(c.Kind)
-			if proto == nil {
-				continue
+		for i, c := range callbacks {
+			kind := AllKinds
+			if c.Kind != AllKinds {
+				proto := schema.ObjectForKind(c.Kind)
+				if proto == nil {
+					continue
+				}
+				kind = proto.Metadata().Kind()
 			}
 
-			_, ok := client.CallbackRegistry[proto.Metadata().Kind()]
-			if !ok {
-				client.CallbackRegistry[proto.Metadata().Kind()] = make(map[Action]Callback)
-			}
-
-			_, ok = client.CallbackRegistry[proto.Metadata().Kind()][c.Action]
-			if ok {
-				return nil, fmt.Errorf("callback for %s %d already set", c.Kind, c.Action)
-			}
-
-			client.CallbackRegistry[proto.Metadata().Kind()][c.Action] = c.Callback
+			c.Sequence = i
+			client.Registry[kind] = append(client.Registry[kind], c)
 		}
 
 		return client, nil
@@ -87,12 +185,27 @@ func (d *reactStore) Create(
 	}
 
 	d.Log.Printf("create %s", obj.PrimaryKey())
-	err := d.runCallback(obj, ActionCreate)
+	err := d.run(phaseBefore, ActionCreate, nil, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.Validate(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.run(phaseCallback, ActionCreate, nil, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := d.Store.Create(ctx, obj, opt...)
 	if err != nil {
 		return nil, err
 	}
 
-	return d.Store.Create(ctx, obj, opt...)
+	return ret, d.after(ActionCreate, nil, ret)
 }
 
 func (d *reactStore) Update(
@@ -106,17 +219,32 @@ func (d *reactStore) Update(
 	}
 
 	d.Log.Printf("update %s", identity.Path())
-	existing, _ := d.Get(ctx, identity)
+	existing, _ := d.Store.Get(ctx, identity)
 	if existing == nil {
 		return nil, constants.ErrNoSuchObject
 	}
 
-	err := d.runCallback(existing, ActionUpdate)
+	err := d.run(phaseBefore, ActionUpdate, existing, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.Validate(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.run(phaseCallback, ActionUpdate, existing, obj)
 	if err != nil {
 		return nil, err
 	}
 
-	return d.Store.Update(ctx, identity, obj, opt...)
+	ret, err := d.Store.Update(ctx, identity, obj, opt...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, d.after(ActionUpdate, existing, ret)
 }
 
 func (d *reactStore) Delete(
@@ -125,17 +253,27 @@ func (d *reactStore) Delete(
 	opt ...options.DeleteOption) error {
 
 	d.Log.Printf("delete %s", identity.Path())
-	existing, _ := d.Get(ctx, identity)
+	existing, _ := d.Store.Get(ctx, identity)
 	if existing == nil {
 		return constants.ErrNoSuchObject
 	}
 
-	err := d.runCallback(existing, ActionDelete)
+	err := d.run(phaseBefore, ActionDelete, existing, nil)
 	if err != nil {
 		return err
 	}
 
-	return d.Store.Delete(ctx, identity, opt...)
+	err = d.run(phaseCallback, ActionDelete, existing, nil)
+	if err != nil {
+		return err
+	}
+
+	err = d.Store.Delete(ctx, identity, opt...)
+	if err != nil {
+		return err
+	}
+
+	return d.after(ActionDelete, existing, nil)
 }
 
 func (d *reactStore) Get(
@@ -144,7 +282,17 @@ func (d *reactStore) Get(
 	opt ...options.GetOption) (store.Object, error) {
 
 	d.Log.Printf("get %s", identity.Path())
-	return d.Store.Get(ctx, identity, opt...)
+	ret, err := d.Store.Get(ctx, identity, opt...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.run(phaseCallback, ActionGet, nil, ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
 }
 
 func (d *reactStore) List(
@@ -153,19 +301,187 @@ func (d *reactStore) List(
 	opt ...options.ListOption) (store.ObjectList, error) {
 
 	d.Log.Printf("list %s", identity.Type())
-	return d.Store.List(ctx, identity, opt...)
+	ret, err := d.Store.List(ctx, identity, opt...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, o := range ret {
+		err = d.run(phaseCallback, ActionList, nil, o)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ret, nil
 }
 
-func (d *reactStore) runCallback(obj store.Object, action Action) error {
-	_, ok := d.CallbackRegistry[obj.Metadata().Kind()]
+// ListPage runs the list callbacks on the objects of the page
+func (d *reactStore) ListPage(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.ListOption) (*store.Page, error) {
+
+	d.Log.Printf("list page %s", identity.Type())
+
+	pager, ok := d.Store.(store.Pager)
 	if !ok {
-		return nil
+		return nil, constants.ErrNotSupported
+	}
+
+	ret, err := pager.ListPage(ctx, identity, opt...)
+	if err != nil {
+		return nil, err
 	}
 
-	_, ok = d.CallbackRegistry[obj.Metadata().Kind()][action]
+	for _, o := range ret.Items {
+		err = d.run(phaseCallback, ActionList, nil, o)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
+
+// Count counts the stored objects, the list callbacks do not run
+func (d *reactStore) Count(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.ListOption) (int, error) {
+
+	d.Log.Printf("count %s", identity.Type())
+
+	return store.Count(ctx, d.Store, identity, opt...)
+}
+
+// Aggregate aggregates the stored objects, the list callbacks do not run
+func (d *reactStore) Aggregate(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	agg store.Aggregation,
+	opt ...options.ListOption) ([]store.AggregateResult, error) {
+
+	d.Log.Printf("aggregate %s", identity.Type())
+
+	return store.AggregateList(ctx, d.Store, identity, agg, opt...)
+}
+
+// run calls the callbacks of the phase registered for the kind
+// of the objects and for all kinds, stopping at the first error
+func (d *reactStore) run(phase _Phase, action Action, old store.Object, new store.Object) error {
+	obj := new
+	if obj == nil || (phase == phaseCallback && action == ActionUpdate) {
+		obj = old
+	}
+
+	for _, r := range d.registered(obj.Metadata().Kind(), phase, action) {
+		var err error
+		if r.Hook != nil {
+			err = r.Hook(old, new, d)
+		} else {
+			err = r.Callback(obj, d)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// after runs the after-hooks of a committed write
+func (d *reactStore) after(action Action, old store.Object, new store.Object) error {
+	err := d.run(phaseAfter, action, old, new)
+	if err != nil {
+		return &AfterHookError{Err: err}
+	}
+
+	return nil
+}
+
+// registered returns the callbacks matching the kind,
+// phase and action in priority order
+func (d *reactStore) registered(kind string, phase _Phase, action Action) []_Register {
+	res := []_Register{}
+	for _, k := range []string{kind, AllKinds} {
+		for _, r := range d.Registry[k] {
+			if r.Phase == phase && (r.Action == action || r.Action == ActionAll) {
+				res = append(res, r)
+			}
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Priority != res[j].Priority {
+			return res[i].Priority < res[j].Priority
+		}
+		return res[i].Sequence < res[j].Sequence
+	})
+
+	return res
+}
+
+func (d *reactStore) Watch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.WatchOption) (<-chan store.Event, error) {
+
+	d.Log.Printf("watch %s", identity.Path())
+
+	watcher, ok := d.Store.(store.Watcher)
+	if !ok {
+		return nil, constants.ErrNotSupported
+	}
+
+	return watcher.Watch(ctx, identity, opt...)
+}
+
+// Patch applies the patch as a revision guarded Update,
+// running the update hooks and callbacks on the patched object
+func (d *reactStore) Patch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	patch store.Patch,
+	opt ...options.UpdateOption) (store.Object, error) {
+
+	d.Log.Printf("patch %s", identity.Path())
+
+	return store.PatchUpdate(ctx, d.Schema, _Unshaped{d}, identity, patch, opt...)
+}
+
+// _Unshaped reads the objects past the read callbacks
+// so that patches apply to the stored objects
+type _Unshaped struct {
+	*reactStore
+}
+
+func (u _Unshaped) Get(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.GetOption) (store.Object, error) {
+
+	return u.Store.Get(ctx, identity, opt...)
+}
+
+func (d *reactStore) Txn(
+	ctx context.Context,
+	fn func(store.Store) error) error {
+
+	d.Log.Printf("txn")
+
+	transactional, ok := d.Store.(store.Transactional)
 	if !ok {
-		return nil
+		return constants.ErrNotSupported
 	}
 
-	return d.CallbackRegistry[obj.Metadata().Kind()][action](obj, d)
+	return transactional.Txn(ctx, func(tx store.Store) error {
+		return fn(&reactStore{
+			Schema:   d.Schema,
+			Store:    tx,
+			Log:      d.Log,
+			Registry: d.Registry,
+		})
+	})
 }
@@ -2,11 +2,15 @@ package react_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/memory"
+	"github.com/wazofski/gostorz/react"
 	"github.com/wazofski/gostorz/store"
 )
 
@@ -75,4 +79,335 @@ var _ = Describe("react", func() {
 		Expect(ret).ToNot(BeNil())
 		Expect(err).To(BeNil())
 	})
+	It("can validate on CREATE and UPDATE", func() {
+		world := generated.WorldFactory()
+		_, err := str.Create(ctx, world)
+		verr := &store.ValidationError{}
+		Expect(errors.As(err, &verr)).To(BeTrue())
+		Expect(verr.Path).To(Equal("external.name"))
+
+		world.External().SetName("validated")
+		world.External().Nested().SetCounter(1001)
+		_, err = str.Create(ctx, world)
+		Expect(errors.As(err, &verr)).To(BeTrue())
+		Expect(verr.Path).To(Equal("external.nested.counter"))
+
+		world.External().Nested().SetCounter(1000)
+		ret, err := str.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		world = ret.(generated.World)
+		world.Internal().SetList([]generated.NestedWorld{
+			generated.NestedWorldFactory(),
+			generated.NestedWorldFactory(),
+		})
+		world.Internal().List()[1].SetColor("purple")
+		_, err = str.Update(ctx, world.Metadata().Identity(), world)
+		Expect(errors.As(err, &verr)).To(BeTrue())
+		Expect(verr.Path).To(Equal("internal.list.1.color"))
+	})
+
+})
+
+var _ = Describe("react hooks", func() {
+
+	var hooked store.Store
+	var committed []string
+
+	BeforeEach(func() {
+		committed = []string{}
+		record := func(phase string) react.Callback {
+			return func(obj store.Object, st store.Store) error {
+				committed = append(committed, fmt.Sprintf("%s %s %d",
+					phase, obj.PrimaryKey(), obj.Metadata().Revision()))
+				return nil
+			}
+		}
+
+		hooked = store.New(
+			generated.Schema(),
+			react.ReactFactory(
+				store.New(generated.Schema(), memory.Factory()),
+				react.BeforeCreate(generated.WorldKind(),
+					func(obj store.Object, st store.Store) error {
+						world := obj.(generated.World)
+						if world.External().Name() == "vetoed" {
+							return fmt.Errorf("vetoed")
+						}
+						if len(world.External().Name()) == 0 {
+							world.External().SetName("defaulted")
+						}
+						return nil
+					}),
+				react.BeforeUpdate(generated.WorldKind(),
+					func(old store.Object, new store.Object, st store.Store) error {
+						before := old.(generated.World).External().Nested().Counter()
+						after := new.(generated.World).External().Nested().Counter()
+						if after < before {
+							return fmt.Errorf("counter cannot decrease")
+						}
+						new.(generated.World).Internal().SetDescription(
+							fmt.Sprintf("%d -> %d", before, after))
+						return nil
+					}),
+				react.BeforeDelete(generated.WorldKind(),
+					func(obj store.Object, st store.Store) error {
+						if obj.(generated.World).External().Nested().Counter() > 5 {
+							return fmt.Errorf("cannot delete")
+						}
+						return nil
+					}),
+				react.AfterCreate(generated.WorldKind(), record("created")),
+				react.AfterUpdate(generated.WorldKind(),
+					func(old store.Object, new store.Object, st store.Store) error {
+						return record("updated")(new, st)
+					}),
+				react.AfterDelete(generated.WorldKind(), record("deleted")),
+			))
+	})
+
+	It("can default and veto on BeforeCreate", func() {
+		ret, err := hooked.Create(ctx, generated.WorldFactory())
+		Expect(err).To(BeNil())
+		Expect(ret.PrimaryKey()).To(Equal("defaulted"))
+
+		world := generated.WorldFactory()
+		world.External().SetName("vetoed")
+		_, err = hooked.Create(ctx, world)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(Equal("vetoed"))
+
+		_, err = hooked.Get(ctx, generated.WorldIdentity("vetoed"))
+		Expect(err).ToNot(BeNil())
+		Expect(committed).To(Equal([]string{"created defaulted 1"}))
+	})
+
+	It("can compare old and new on BeforeUpdate", func() {
+		world := generated.WorldFactory()
+		world.External().Nested().SetCounter(3)
+		ret, err := hooked.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		world = ret.(generated.World)
+		world.External().Nested().SetCounter(7)
+		ret, err = hooked.Update(ctx, world.Metadata().Identity(), world)
+		Expect(err).To(BeNil())
+		Expect(ret.(generated.World).Internal().Description()).To(Equal("3 -> 7"))
+
+		world = ret.(generated.World)
+		world.External().Nested().SetCounter(1)
+		_, err = hooked.Update(ctx, world.Metadata().Identity(), world)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(Equal("counter cannot decrease"))
+
+		Expect(committed).To(Equal([]string{
+			"created defaulted 1",
+			"updated defaulted 2",
+		}))
+	})
+
+	It("can veto on BeforeDelete and see the deleted object after", func() {
+		world := generated.WorldFactory()
+		world.External().Nested().SetCounter(6)
+		_, err := hooked.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		err = hooked.Delete(ctx, generated.WorldIdentity("defaulted"))
+		Expect(err).ToNot(BeNil())
+
+		world = generated.WorldFactory()
+		world.External().SetName("deletable")
+		_, err = hooked.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		err = hooked.Delete(ctx, generated.WorldIdentity("deletable"))
+		Expect(err).To(BeNil())
+
+		Expect(committed).To(Equal([]string{
+			"created defaulted 1",
+			"created deletable 1",
+			"deleted deletable 1",
+		}))
+	})
+
+	It("can return committed objects when after-hooks fail", func() {
+		backend := store.New(generated.Schema(), memory.Factory())
+		failing := store.New(
+			generated.Schema(),
+			react.ReactFactory(backend,
+				react.AfterCreate(generated.WorldKind(),
+					func(obj store.Object, st store.Store) error {
+						return constants.ErrConflict
+					}),
+				react.AfterUpdate(generated.WorldKind(),
+					func(old store.Object, new store.Object, st store.Store) error {
+						return fmt.Errorf("%w: update failed", constants.ErrConflict)
+					}),
+				react.AfterDelete(generated.WorldKind(),
+					func(obj store.Object, st store.Store) error {
+						return fmt.Errorf("delete failed")
+					}),
+			))
+
+		world := generated.WorldFactory()
+		world.External().SetName("committed")
+		ret, err := failing.Create(ctx, world)
+		hookErr := &react.AfterHookError{}
+		Expect(errors.As(err, &hookErr)).To(BeTrue())
+		Expect(errors.Is(err, constants.ErrConflict)).To(BeTrue())
+		Expect(ret).ToNot(BeNil())
+		Expect(ret.Metadata().Revision()).To(Equal(int64(1)))
+
+		ret.(generated.World).External().SetDescription("patched")
+		ret, err = failing.Update(ctx, ret.Metadata().Identity(), ret)
+		Expect(errors.As(err, &hookErr)).To(BeTrue())
+		Expect(hookErr.Err.Error()).To(HaveSuffix("update failed"))
+		Expect(ret.Metadata().Revision()).To(Equal(int64(2)))
+
+		ret, err = failing.(store.Patcher).Patch(ctx,
+			generated.WorldIdentity("committed"),
+			store.MergePatch([]byte(`{"external": {"description": "merged"}}`)))
+		Expect(errors.As(err, &hookErr)).To(BeTrue())
+		Expect(ret.Metadata().Revision()).To(Equal(int64(3)))
+
+		err = failing.Delete(ctx, generated.WorldIdentity("committed"))
+		Expect(errors.As(err, &hookErr)).To(BeTrue())
+		Expect(hookErr.Err.Error()).To(Equal("delete failed"))
+
+		_, err = backend.Get(ctx, generated.WorldIdentity("committed"))
+		Expect(errors.Is(err, constants.ErrNoSuchObject)).To(BeTrue())
+	})
+
+	It("can run several callbacks by priority", func() {
+		order := []string{}
+		called := func(name string) react.Callback {
+			return func(obj store.Object, st store.Store) error {
+				order = append(order, name)
+				return nil
+			}
+		}
+
+		ordered := store.New(
+			generated.Schema(),
+			react.ReactFactory(
+				store.New(generated.Schema(), memory.Factory()),
+				react.AfterCreate(generated.WorldKind(), called("after")),
+				react.AfterCreate(generated.WorldKind(), called("after first")).WithPriority(-1),
+				react.Subscribe(generated.WorldKind(), react.ActionCreate, called("late")).WithPriority(10),
+				react.Subscribe(generated.WorldKind(), react.ActionCreate, called("default")),
+				react.Subscribe(generated.WorldKind(), react.ActionCreate, called("default again")),
+				react.BeforeCreate(generated.WorldKind(), called("before")),
+			))
+
+		world := generated.WorldFactory()
+		world.External().SetName("ordered")
+		_, err := ordered.Create(ctx, world)
+		Expect(err).To(BeNil())
+		Expect(order).To(Equal([]string{
+			"before",
+			"default",
+			"default again",
+			"late",
+			"after first",
+			"after",
+		}))
+	})
+})
+
+var _ = Describe("react subscriptions", func() {
+
+	It("can subscribe to all kinds and actions", func() {
+		audit := []string{}
+		wildcard := store.New(
+			generated.Schema(),
+			react.ReactFactory(
+				store.New(generated.Schema(), memory.Factory()),
+				react.Subscribe(react.AllKinds, react.ActionAll,
+					func(obj store.Object, st store.Store) error {
+						audit = append(audit, fmt.Sprintf("%s %s",
+							obj.Metadata().Kind(), obj.PrimaryKey()))
+						return nil
+					}),
+				react.AfterCreate(react.AllKinds,
+					func(obj store.Object, st store.Store) error {
+						audit = append(audit, "created")
+						return nil
+					}),
+			))
+
+		world := generated.WorldFactory()
+		world.External().SetName("audited")
+		_, err := wildcard.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		second := generated.SecondWorldFactory()
+		second.External().SetName("audited")
+		_, err = wildcard.Create(ctx, second)
+		Expect(err).To(BeNil())
+
+		_, err = wildcard.Get(ctx, generated.WorldIdentity("audited"))
+		Expect(err).To(BeNil())
+
+		_, err = wildcard.List(ctx, generated.SecondWorldIdentity(""))
+		Expect(err).To(BeNil())
+
+		err = wildcard.Delete(ctx, generated.WorldIdentity("audited"))
+		Expect(err).To(BeNil())
+
+		Expect(audit).To(Equal([]string{
+			"World audited", "created",
+			"SecondWorld audited", "created",
+			"World audited",
+			"SecondWorld audited",
+			"World audited",
+		}))
+	})
+
+	It("can shape and reject reads", func() {
+		shaped := store.New(
+			generated.Schema(),
+			react.ReactFactory(
+				store.New(generated.Schema(), memory.Factory()),
+				react.Subscribe(generated.WorldKind(), react.ActionGet,
+					func(obj store.Object, st store.Store) error {
+						world := obj.(generated.World)
+						if world.External().Name() == "hidden" {
+							return constants.ErrNoSuchObject
+						}
+						world.Internal().SetDescription("redacted")
+						return nil
+					}),
+				react.Subscribe(generated.WorldKind(), react.ActionList,
+					func(obj store.Object, st store.Store) error {
+						obj.(generated.World).Internal().SetDescription("listed")
+						return nil
+					}),
+			))
+
+		for _, name := range []string{"shown", "hidden"} {
+			world := generated.WorldFactory()
+			world.External().SetName(name)
+			world.Internal().SetDescription("secret")
+			_, err := shaped.Create(ctx, world)
+			Expect(err).To(BeNil())
+		}
+
+		ret, err := shaped.Get(ctx, generated.WorldIdentity("shown"))
+		Expect(err).To(BeNil())
+		Expect(ret.(generated.World).Internal().Description()).To(Equal("redacted"))
+
+		_, err = shaped.Get(ctx, generated.WorldIdentity("hidden"))
+		Expect(err).To(Equal(constants.ErrNoSuchObject))
+
+		list, err := shaped.List(ctx, generated.WorldIdentity(""))
+		Expect(err).To(BeNil())
+		Expect(len(list)).To(Equal(2))
+		for _, o := range list {
+			Expect(o.(generated.World).Internal().Description()).To(Equal("listed"))
+		}
+
+		// writes are not affected by read callbacks
+		err = shaped.Delete(ctx, generated.WorldIdentity("hidden"))
+		Expect(err).To(BeNil())
+	})
 })
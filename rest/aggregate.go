@@ -0,0 +1,44 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/store"
+	"github.com/wazofski/gostorz/store/options"
+)
+
+// handleAggregate replies the results of the JSON aggregation
+// over the objects of the kind matching the List filters
+func (d *_Server) handleAggregate(
+	w http.ResponseWriter,
+	kind string,
+	arg string,
+	opts []options.ListOption) {
+
+	agg := store.Aggregation{}
+	err := json.Unmarshal([]byte(arg), &agg)
+	if err != nil {
+		reportError(w,
+			fmt.Errorf("%w: %s", constants.ErrInvalidAggr, err),
+			http.StatusBadRequest)
+		return
+	}
+
+	ret, err := d.Store.(store.Aggregator).Aggregate(
+		d.Context,
+		store.ObjectIdentity(fmt.Sprintf("%s/", strings.ToLower(kind))),
+		agg,
+		opts...)
+
+	if err != nil {
+		reportError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	resp, _ := json.Marshal(ret)
+	writeResponse(w, resp)
+}
@@ -0,0 +1,315 @@
+package rest
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/wazofski/gostorz/internal/constants"
+	"golang.org/x/exp/slices"
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject string
+	Roles   []string
+	Claims  map[string]interface{}
+}
+
+// Authenticator resolves the caller of a request,
+// any error rejects the request with 401 Unauthorized
+type Authenticator interface {
+	Authenticate(*http.Request) (*Principal, error)
+}
+
+// Authorizer decides if the principal, nil when the server has no
+// Authenticator, can run the action on the kind. Errors reject the
+// request with 403 Forbidden
+type Authorizer func(principal *Principal, kind string, action Action) error
+
+// ServerOption configures the server, see TypeMethods
+type ServerOption interface {
+	applyServer(*_Server)
+}
+
+type _AuthOption struct {
+	Authenticator Authenticator
+	Authorizer    Authorizer
+}
+
+func (o _AuthOption) applyServer(d *_Server) {
+	if o.Authenticator != nil {
+		d.Authenticator = o.Authenticator
+	}
+	if o.Authorizer != nil {
+		d.Authorizer = o.Authorizer
+	}
+}
+
+// Authenticate requires every request to pass the authenticator
+func Authenticate(authenticator Authenticator) ServerOption {
+	return _AuthOption{Authenticator: authenticator}
+}
+
+// Authorize checks every action on an exposed kind with the authorizer
+func Authorize(authorizer Authorizer) ServerOption {
+	return _AuthOption{Authorizer: authorizer}
+}
+
+type principalKey struct{}
+
+// PrincipalFrom returns the authenticated caller of the request context
+func PrincipalFrom(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// BearerAuthenticator verifies the bearer token of the
+// Authorization header with the function
+type BearerAuthenticator func(token string) (*Principal, error)
+
+func (f BearerAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	if len(token) == 0 {
+		return nil, constants.ErrUnauthorized
+	}
+
+	return f(token)
+}
+
+// APIKeys authenticates requests carrying one of the keys either
+// as a bearer token or in the X-API-Key header
+func APIKeys(keys map[string]Principal) Authenticator {
+	return BearerAuthenticator(func(token string) (*Principal, error) {
+		principal, ok := keys[token]
+		if !ok {
+			return nil, constants.ErrUnauthorized
+		}
+
+		return &principal, nil
+	})
+}
+
+func bearerToken(r *http.Request) string {
+	key := r.Header.Get("X-API-Key")
+	if len(key) > 0 {
+		return key
+	}
+
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+
+	return ""
+}
+
+var jwtAlgorithms = map[string]func() hash.Hash{
+	"HS256": sha256.New,
+	"HS384": sha512.New384,
+	"HS512": sha512.New,
+}
+
+// JWT authenticates bearer tokens signed with the HMAC key.
+// The sub claim is the subject, the roles claim lists the roles
+// and the exp and nbf claims are enforced when present
+func JWT(key []byte) Authenticator {
+	return BearerAuthenticator(func(token string) (*Principal, error) {
+		claims, err := verifyJWT(key, token)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", constants.ErrUnauthorized, err)
+		}
+
+		principal := &Principal{
+			Claims: claims,
+		}
+
+		principal.Subject, _ = claims["sub"].(string)
+		roles, _ := claims["roles"].([]interface{})
+		for _, r := range roles {
+			role, ok := r.(string)
+			if ok {
+				principal.Roles = append(principal.Roles, role)
+			}
+		}
+
+		return principal, nil
+	})
+}
+
+// SignJWT makes an HS256 token of the claims
+func SignJWT(key []byte, claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(
+		sign(sha256.New, key, unsigned)), nil
+}
+
+func verifyJWT(key []byte, token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	header := struct {
+		Alg string `json:"alg"`
+	}{}
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, ok := jwtAlgorithms[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm %s", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal(signature, sign(algorithm, key, parts[0]+"."+parts[1])) {
+		return nil, errors.New("invalid signature")
+	}
+
+	claims := map[string]interface{}{}
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return nil, err
+	}
+
+	now := float64(time.Now().Unix())
+	exp, ok := claims["exp"].(float64)
+	if ok && now >= exp {
+		return nil, errors.New("token expired")
+	}
+
+	nbf, ok := claims["nbf"].(float64)
+	if ok && now < nbf {
+		return nil, errors.New("token not valid yet")
+	}
+
+	return claims, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func sign(algorithm func() hash.Hash, key []byte, data string) []byte {
+	mac := hmac.New(algorithm, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Grant allows a role the actions on the kinds,
+// no kinds or AllKinds grant the actions on every kind
+type Grant struct {
+	Role    string
+	Kinds   []string
+	Actions []Action
+}
+
+// AllKinds grants the actions on every exposed kind
+const AllKinds = "*"
+
+// RBAC authorizes the principals holding a role granted the action
+func RBAC(grants ...Grant) Authorizer {
+	return func(principal *Principal, kind string, action Action) error {
+		if principal == nil {
+			return constants.ErrForbidden
+		}
+
+		for _, g := range grants {
+			if !slices.Contains(principal.Roles, g.Role) ||
+				!slices.Contains(g.Actions, action) {
+				continue
+			}
+
+			if len(g.Kinds) == 0 || slices.Contains(g.Kinds, AllKinds) {
+				return nil
+			}
+
+			for _, k := range g.Kinds {
+				if strings.EqualFold(k, kind) {
+					return nil
+				}
+			}
+		}
+
+		return constants.ErrForbidden
+	}
+}
+
+// authenticate runs the authenticator ahead of every handler
+// and keeps the principal in the request context
+func (d *_Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.Authenticator == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, err := d.Authenticator.Authenticate(r)
+		if err != nil {
+			log.Printf("%s %s: %s", strings.ToLower(r.Method), r.URL, err)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			reportError(w, constants.ErrUnauthorized, http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(
+			context.WithValue(r.Context(), principalKey{}, principal)))
+	})
+}
+
+// authorize checks the action on the kind, reporting 403 when denied
+func (d *_Server) authorize(w http.ResponseWriter, r *http.Request, kind string, action Action) bool {
+	if !d.allowed(r, kind, action) {
+		reportError(w, constants.ErrForbidden, http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// allowed checks the action on the kind without replying
+func (d *_Server) allowed(r *http.Request, kind string, action Action) bool {
+	if d.Authorizer == nil {
+		return true
+	}
+
+	err := d.Authorizer(PrincipalFrom(r.Context()), kind, action)
+	if err != nil {
+		log.Printf("%s %s: %s", strings.ToLower(r.Method), r.URL, err)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,92 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/react"
+	"github.com/wazofski/gostorz/store"
+)
+
+// ErrorResponse is the JSON body of every error reply
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail carries a stable code, see internal/constants.
+// Validation errors also report the violating property path
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Path    string `json:"path,omitempty"`
+}
+
+var codeStatus = map[string]int{
+	constants.CodeNotFound:         http.StatusNotFound,
+	constants.CodeAlreadyExists:    http.StatusConflict,
+	constants.CodeConflict:         http.StatusPreconditionFailed,
+	constants.CodeInvalidFilter:    http.StatusBadRequest,
+	constants.CodeInvalidObject:    http.StatusBadRequest,
+	constants.CodeInvalidPath:      http.StatusBadRequest,
+	constants.CodeInvalidPatch:     http.StatusBadRequest,
+	constants.CodeInvalidToken:     http.StatusBadRequest,
+	constants.CodeInvalidAggr:      http.StatusBadRequest,
+	constants.CodeMethodNotAllowed: http.StatusMethodNotAllowed,
+	constants.CodeNotSupported:     http.StatusNotImplemented,
+	constants.CodeUnknownKind:      http.StatusBadRequest,
+	constants.CodeUnauthorized:     http.StatusUnauthorized,
+	constants.CodeForbidden:        http.StatusForbidden,
+	constants.CodeValidation:       http.StatusBadRequest,
+	constants.CodeAfterHook:        http.StatusInternalServerError,
+}
+
+// errorResponse describes the error, falling back to
+// the status for errors without a stable code
+func errorResponse(err error, status int) (ErrorResponse, int) {
+	res := ErrorResponse{
+		Error: ErrorDetail{
+			Code:    constants.ErrorCode(err),
+			Message: err.Error(),
+		},
+	}
+
+	verr := &store.ValidationError{}
+	if errors.As(err, &verr) {
+		res.Error.Code = constants.CodeValidation
+		res.Error.Message = verr.Reason
+		res.Error.Path = verr.Path
+	}
+
+	// the write is committed, so the hook error is
+	// not reported as a failure of the request
+	aerr := &react.AfterHookError{}
+	if errors.As(err, &aerr) {
+		res.Error.Code = constants.CodeAfterHook
+		res.Error.Message = aerr.Err.Error()
+		res.Error.Path = ""
+	}
+
+	code, ok := codeStatus[res.Error.Code]
+	if ok {
+		return res, code
+	}
+
+	res.Error.Code = constants.CodeInternal
+	if status < http.StatusInternalServerError {
+		res.Error.Code = constants.CodeBadRequest
+	}
+
+	return res, status
+}
+
+func reportError(w http.ResponseWriter, err error, status int) {
+	res, code := errorResponse(err, status)
+	data, _ := json.Marshal(res)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	w.Write(data)
+}
@@ -0,0 +1,79 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/store"
+)
+
+// ETag is the strong entity tag of the object, a hash of its JSON content
+func ETag(obj store.Object) (string, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+
+	return etagOf(data), nil
+}
+
+func etagOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf(`"%s"`, hex.EncodeToString(sum[:16]))
+}
+
+// etagMatch tells if the If-Match or If-None-Match header value
+// lists the tag, weak tags compare by their opaque part
+func etagMatch(header string, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notModified answers 304 when If-None-Match lists the tag
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if len(header) == 0 || !etagMatch(header, etag) {
+		return false
+	}
+
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// ifMatch checks the If-Match header against the stored object and
+// returns its revision, so the write fails when it changes meanwhile
+func (d *_Server) ifMatch(r *http.Request, identity store.ObjectIdentity) (*int64, error) {
+	header := r.Header.Get("If-Match")
+	if len(header) == 0 {
+		return nil, nil
+	}
+
+	existing, err := d.Store.Get(d.Context, identity)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", constants.ErrConflict, err)
+	}
+
+	etag, err := ETag(existing)
+	if err != nil {
+		return nil, err
+	}
+
+	if !etagMatch(header, etag) {
+		return nil, fmt.Errorf("%w: etag mismatch", constants.ErrConflict)
+	}
+
+	revision := existing.Metadata().Revision()
+	return &revision, nil
+}
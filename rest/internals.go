@@ -127,3 +127,83 @@ func (d *internalStore) List(
 
 	return d.Store.List(ctx, identity, opt...)
 }
+
+func (d *internalStore) ListPage(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.ListOption) (*store.Page, error) {
+
+	d.Log.Printf("list page %s", identity.Type())
+
+	pager, ok := d.Store.(store.Pager)
+	if ok {
+		return pager.ListPage(ctx, identity, opt...)
+	}
+
+	// plain listings of stores unable to page
+	copt := options.CommonOptionHolderFactory()
+	for _, o := range opt {
+		err := o.ApplyFunction()(&copt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(copt.Continue) > 0 || copt.TotalCount {
+		return nil, constants.ErrNotSupported
+	}
+
+	ret, err := d.Store.List(ctx, identity, opt...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &store.Page{Items: ret}, nil
+}
+
+func (d *internalStore) Aggregate(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	agg store.Aggregation,
+	opt ...options.ListOption) ([]store.AggregateResult, error) {
+
+	d.Log.Printf("aggregate %s", identity.Type())
+
+	return store.AggregateList(ctx, d.Store, identity, agg, opt...)
+}
+
+func (d *internalStore) Watch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.WatchOption) (<-chan store.Event, error) {
+
+	d.Log.Printf("watch %s", identity.Path())
+
+	watcher, ok := d.Store.(store.Watcher)
+	if !ok {
+		return nil, constants.ErrNotSupported
+	}
+
+	return watcher.Watch(ctx, identity, opt...)
+}
+
+func (d *internalStore) Patch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	patch store.Patch,
+	opt ...options.UpdateOption) (store.Object, error) {
+
+	d.Log.Printf("patch %s", identity.Path())
+
+	patcher, ok := d.Store.(store.Patcher)
+	if !ok {
+		return nil, constants.ErrNotSupported
+	}
+
+	patch, err := stampPatch(patch, utils.Timestamp())
+	if err != nil {
+		return nil, err
+	}
+
+	return patcher.Patch(ctx, identity, patch, opt...)
+}
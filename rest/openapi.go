@@ -0,0 +1,50 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/internal/openapi"
+	"github.com/wazofski/gostorz/store"
+)
+
+const OpenAPIPath = openapi.Path
+
+// OpenAPI builds an OpenAPI 3 document describing the actions exposed per kind.
+// Components are the JSON schemas of the model types keyed by name,
+// as produced by mgen.
+func OpenAPI(components map[string]interface{}, exposed map[string][]Action) ([]byte, error) {
+	return openapi.Document(components, exposed)
+}
+
+func openAPIComponents(schema store.SchemaHolder) map[string]interface{} {
+	res := map[string]interface{}{}
+
+	describer, ok := schema.(store.Describer)
+	if !ok {
+		return res
+	}
+
+	err := json.Unmarshal([]byte(describer.OpenAPI()), &res)
+	if err != nil {
+		log.Printf("invalid openapi components: %s", err)
+	}
+
+	return res
+}
+
+func makeOpenAPIHandler(spec []byte) _HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		prepResponse(w, r)
+
+		if r.Method != http.MethodGet {
+			reportError(w,
+				constants.ErrInvalidMethod,
+				http.StatusMethodNotAllowed)
+			return
+		}
+
+		writeResponse(w, spec)
+	}
+}
@@ -0,0 +1,165 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/internal/jsonpatch"
+	"github.com/wazofski/gostorz/store"
+	"github.com/wazofski/gostorz/store/options"
+)
+
+// handlePatch applies merge or JSON patches of the external properties,
+// the content type selects the kind of patch
+func (d *_Server) handlePatch(
+	w http.ResponseWriter,
+	r *http.Request,
+	identity store.ObjectIdentity,
+	data []byte) {
+
+	patch, err := patchOf(r.Header.Get("Content-Type"), data)
+	if err != nil {
+		reportError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = externalOnly(patch)
+	if err != nil {
+		reportError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	rev, ok := d.preconditions(w, r, identity)
+	if !ok {
+		return
+	}
+
+	opts := []options.UpdateOption{}
+	if rev != nil {
+		opts = append(opts, options.IfRevision(*rev))
+	}
+
+	patcher, ok := d.Store.(store.Patcher)
+	if !ok {
+		reportError(w, constants.ErrNotSupported, http.StatusNotImplemented)
+		return
+	}
+
+	ret, err := patcher.Patch(d.Context, identity, patch, opts...)
+	if err != nil {
+		reportError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	writeObject(w, r, ret)
+}
+
+// patchOf picks the patch type of the content type,
+// plain JSON arrays are JSON patches and objects merge patches
+func patchOf(contentType string, data []byte) (store.Patch, error) {
+	media := ""
+	if len(contentType) > 0 {
+		var err error
+		media, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return store.Patch{}, fmt.Errorf("%w: %s", constants.ErrInvalidPatch, err)
+		}
+	}
+
+	switch store.PatchType(media) {
+	case store.MergePatchType:
+		return store.MergePatch(data), nil
+	case store.JSONPatchType:
+		return store.JSONPatch(data), nil
+	}
+
+	if len(media) > 0 && media != "application/json" {
+		return store.Patch{}, fmt.Errorf("%w: unsupported content type %s",
+			constants.ErrInvalidPatch, media)
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return store.JSONPatch(data), nil
+	}
+
+	return store.MergePatch(data), nil
+}
+
+// externalOnly rejects patches of anything but the external properties
+func externalOnly(patch store.Patch) error {
+	if patch.Type == store.MergePatchType {
+		doc := map[string]*json.RawMessage{}
+		err := json.Unmarshal(patch.Data, &doc)
+		if err != nil {
+			return fmt.Errorf("%w: %s", constants.ErrInvalidPatch, err)
+		}
+
+		for k := range doc {
+			if k != "external" {
+				return fmt.Errorf("%w: %s cannot be patched", constants.ErrInvalidPatch, k)
+			}
+		}
+
+		return nil
+	}
+
+	ops, err := jsonpatch.Decode(patch.Data)
+	if err != nil {
+		return err
+	}
+
+	for _, op := range ops {
+		paths := []string{op.Path}
+		if op.Op == "move" || op.Op == "copy" {
+			paths = append(paths, op.From)
+		}
+
+		for _, p := range paths {
+			if p != "/external" && !strings.HasPrefix(p, "/external/") {
+				return fmt.Errorf("%w: %s cannot be patched", constants.ErrInvalidPatch, p)
+			}
+		}
+	}
+
+	return nil
+}
+
+// stampPatch extends the patch to set the update time
+func stampPatch(patch store.Patch, timestamp string) (store.Patch, error) {
+	if patch.Type == store.MergePatchType {
+		doc := map[string]interface{}{}
+		err := json.Unmarshal(patch.Data, &doc)
+		if err != nil {
+			return patch, fmt.Errorf("%w: %s", constants.ErrInvalidPatch, err)
+		}
+
+		doc["metadata"] = map[string]interface{}{"updated": timestamp}
+		data, err := json.Marshal(doc)
+		return store.MergePatch(data), err
+	}
+
+	ops, err := jsonpatch.Decode(patch.Data)
+	if err != nil {
+		return patch, err
+	}
+
+	value, err := json.Marshal(timestamp)
+	if err != nil {
+		return patch, err
+	}
+
+	raw := json.RawMessage(value)
+	ops = append(ops, jsonpatch.Operation{
+		Op:    "add",
+		Path:  "/metadata/updated",
+		Value: &raw,
+	})
+
+	data, err := json.Marshal(ops)
+	return store.JSONPatch(data), err
+}
@@ -5,15 +5,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
 	"github.com/gorilla/mux"
 	"golang.org/x/exp/slices"
 
+	"github.com/wazofski/gostorz/filter"
 	"github.com/wazofski/gostorz/internal/constants"
 	"github.com/wazofski/gostorz/internal/logger"
+	"github.com/wazofski/gostorz/internal/openapi"
 	"github.com/wazofski/gostorz/store"
 	"github.com/wazofski/gostorz/store/options"
 	"github.com/wazofski/gostorz/utils"
@@ -22,32 +26,60 @@ import (
 var log = logger.Factory("rest server")
 
 const (
-	PropFilterArg  = "pf"
-	KeyFilterArg   = "kf"
-	IncrementalArg = "inc"
-	PageSizeArg    = "pageSize"
-	PageOffsetArg  = "pageOffset"
-	OrderByArg     = "orderBy"
+	PropFilterArg  = openapi.PropFilterArg
+	KeyFilterArg   = openapi.KeyFilterArg
+	FilterArg      = openapi.FilterArg
+	IncrementalArg = openapi.IncrementalArg
+	PageSizeArg    = openapi.PageSizeArg
+	PageOffsetArg  = openapi.PageOffsetArg
+	ContinueArg    = openapi.ContinueArg
+	TotalArg       = openapi.TotalArg
+	OrderByArg     = openapi.OrderByArg
+	RevisionArg    = openapi.RevisionArg
+	WatchArg       = openapi.WatchArg
+	AggregateArg   = openapi.AggregateArg
+	FieldsArg      = openapi.FieldsArg
+)
+
+// List replies carry the token of the next page
+// and the total count when requested
+const (
+	ContinueHeader = openapi.ContinueHeader
+	TotalHeader    = openapi.TotalHeader
 )
 
 type _HandlerFunc func(http.ResponseWriter, *http.Request)
 
 type _Server struct {
-	Schema  store.SchemaHolder
-	Store   store.Store
-	Context context.Context
-	Router  *mux.Router
-	Exposed map[string][]Action
+	Schema        store.SchemaHolder
+	Store         store.Store
+	Context       context.Context
+	Router        *mux.Router
+	Exposed       map[string][]Action
+	Authenticator Authenticator
+	Authorizer    Authorizer
 }
 
 func (d *_Server) Listen(port int) context.CancelFunc {
+	// cancelled on shutdown to end the streaming watch requests
+	ctx, cancel := context.WithCancel(context.Background())
 	srv := http.Server{
 		Addr:    fmt.Sprintf(":%d", port),
 		Handler: d.Router,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	// bound before returning so the server accepts requests right away
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		log.Printf("error listening: %s", err)
+		return cancel
 	}
 
 	go func() {
-		err := srv.ListenAndServe()
+		err := srv.Serve(listener)
 		if errors.Is(err, http.ErrServerClosed) {
 			log.Printf("server closed")
 		} else if err != nil {
@@ -59,16 +91,19 @@ func (d *_Server) Listen(port int) context.CancelFunc {
 
 	log.Printf("listening on port %d", port)
 
-	return func() { srv.Shutdown(context.Background()) }
+	return func() {
+		cancel()
+		srv.Shutdown(context.Background())
+	}
 }
 
-type Action string
+type Action = openapi.Action
 
 const (
-	ActionCreate Action = http.MethodPost
-	ActionUpdate Action = http.MethodPut
-	ActionDelete Action = http.MethodDelete
-	ActionGet    Action = http.MethodGet
+	ActionCreate = openapi.ActionCreate
+	ActionUpdate = openapi.ActionUpdate
+	ActionDelete = openapi.ActionDelete
+	ActionGet    = openapi.ActionGet
 )
 
 type _TypeMethods struct {
@@ -76,6 +111,16 @@ type _TypeMethods struct {
 	Actions []Action
 }
 
+// actionOf returns the action of the request method,
+// PATCH is a partial ActionUpdate
+func actionOf(r *http.Request) Action {
+	if r.Method == http.MethodPatch {
+		return ActionUpdate
+	}
+
+	return Action(r.Method)
+}
+
 func TypeMethods(kind string, actions ...Action) _TypeMethods {
 	return _TypeMethods{
 		Kind:    kind,
@@ -83,7 +128,11 @@ func TypeMethods(kind string, actions ...Action) _TypeMethods {
 	}
 }
 
-func Server(schema store.SchemaHolder, stor store.Store, exposed ..._TypeMethods) store.Endpoint {
+func (e _TypeMethods) applyServer(d *_Server) {
+	d.Exposed[e.Kind] = e.Actions
+}
+
+func Server(schema store.SchemaHolder, stor store.Store, opts ...ServerOption) store.Endpoint {
 	server := &_Server{
 		Schema:  schema,
 		Store:   store.New(schema, internalFactory(stor)),
@@ -92,9 +141,20 @@ func Server(schema store.SchemaHolder, stor store.Store, exposed ..._TypeMethods
 		Exposed: make(map[string][]Action),
 	}
 
+	exposed := []_TypeMethods{}
+	for _, o := range opts {
+		o.applyServer(server)
+
+		e, ok := o.(_TypeMethods)
+		if ok {
+			exposed = append(exposed, e)
+		}
+	}
+
+	server.Router.Use(server.authenticate)
+
 	addHandler(server.Router, "/id/{id}", makeIdHandler(server))
 	for _, e := range exposed {
-		server.Exposed[e.Kind] = e.Actions
 
 		addHandler(server.Router,
 			fmt.Sprintf("/%s/{pkey}", strings.ToLower(e.Kind)),
@@ -107,6 +167,13 @@ func Server(schema store.SchemaHolder, stor store.Store, exposed ..._TypeMethods
 			makeTypeHandler(server, e.Kind, e.Actions))
 	}
 
+	spec, err := OpenAPI(openAPIComponents(schema), server.Exposed)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	addHandler(server.Router, OpenAPIPath, makeOpenAPIHandler(spec))
+
 	return server
 }
 
@@ -118,24 +185,30 @@ func makeIdHandler(server *_Server) _HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		prepResponse(w, r)
 		id := store.ObjectIdentity(mux.Vars(r)["id"])
-		existing, _ := server.Store.Get(server.Context, id)
 
-		var robject store.Object = nil
-		if existing != nil {
-			kind := existing.Metadata().Kind()
-			data, err := utils.ReadStream(r.Body)
-			if err == nil {
-				robject, _ = utils.UnmarshalObject(data, server.Schema, kind)
-			}
+		// the kind is needed to authorize, so fail closed
+		// when the object cannot be looked up
+		existing, err := server.Store.Get(server.Context, id)
+		if err != nil {
+			reportError(w, err, http.StatusInternalServerError)
+			return
+		}
 
-			// method validation
-			objMethods := server.Exposed[kind]
-			if objMethods == nil || !slices.Contains(objMethods, Action(r.Method)) {
-				reportError(w,
-					constants.ErrInvalidMethod,
-					http.StatusMethodNotAllowed)
-				return
-			}
+		// objects of kinds not exposed or not allowed to the
+		// caller are reported missing, so ids cannot be probed
+		kind := existing.Metadata().Kind()
+		if !slices.Contains(server.Exposed[kind], actionOf(r)) ||
+			!server.allowed(r, kind, actionOf(r)) {
+			reportError(w, constants.ErrNoSuchObject, http.StatusNotFound)
+			return
+		}
+
+		data, _ := utils.ReadStream(r.Body)
+		robject, _ := utils.UnmarshalObject(data, server.Schema, kind)
+
+		if r.Method == http.MethodPatch {
+			server.handlePatch(w, r, id, data)
+			return
 		}
 
 		server.handlePath(w, r, id, robject)
@@ -153,13 +226,22 @@ func makeObjectHandler(server *_Server, t string, methods []Action) _HandlerFunc
 		}
 
 		// method validation
-		if !slices.Contains(methods, Action(r.Method)) {
+		if !slices.Contains(methods, actionOf(r)) {
 			reportError(w,
 				constants.ErrInvalidMethod,
 				http.StatusMethodNotAllowed)
 			return
 		}
 
+		if !server.authorize(w, r, t, actionOf(r)) {
+			return
+		}
+
+		if r.Method == http.MethodPatch {
+			server.handlePatch(w, r, id, data)
+			return
+		}
+
 		server.handlePath(w, r, id, robject)
 	}
 }
@@ -176,31 +258,29 @@ func makeTypeHandler(server *_Server, t string, methods []Action) _HandlerFunc {
 			return
 		}
 
+		if !server.authorize(w, r, t, Action(r.Method)) {
+			return
+		}
+
 		switch r.Method {
 		case http.MethodGet:
-			opts := []options.ListOption{}
-
 			vals := r.URL.Query()
-			filter, ok := vals[PropFilterArg]
-			if ok {
-				flt := options.PropFilterSetting{}
-				err := json.Unmarshal([]byte(filter[0]), &flt)
-				if err != nil {
-					reportError(w, err, http.StatusBadRequest)
-					return
-				}
-				opts = append(opts, options.PropFilter(flt.Key, flt.Value))
+			opts, watchOpts, err := filterOptions(vals)
+			if err != nil {
+				reportError(w, err, http.StatusBadRequest)
+				return
 			}
 
-			keyFilter, ok := vals[KeyFilterArg]
+			watch, ok := vals[WatchArg]
+			if ok && watch[0] == "true" {
+				server.handleWatch(w, r, t, watchOpts)
+				return
+			}
+
+			aggregate, ok := vals[AggregateArg]
 			if ok {
-				flt := options.KeyFilterSetting{}
-				err := json.Unmarshal([]byte(keyFilter[0]), &flt)
-				if err != nil {
-					reportError(w, err, http.StatusBadRequest)
-					return
-				}
-				opts = append(opts, options.KeyFilter(flt...))
+				server.handleAggregate(w, t, aggregate[0], opts)
+				return
 			}
 
 			pageSize, ok := vals[PageSizeArg]
@@ -215,6 +295,21 @@ func makeTypeHandler(server *_Server, t string, methods []Action) _HandlerFunc {
 				opts = append(opts, options.PageOffset(ps))
 			}
 
+			fields := fieldsArg(vals)
+			if len(fields) > 0 {
+				opts = append(opts, options.Fields(fields...))
+			}
+
+			token, ok := vals[ContinueArg]
+			if ok {
+				opts = append(opts, options.Continue(token[0]))
+			}
+
+			total, ok := vals[TotalArg]
+			if ok && total[0] == "true" {
+				opts = append(opts, options.TotalCount())
+			}
+
 			orderBy, ok := vals[OrderByArg]
 			if ok {
 				ob := orderBy[0]
@@ -234,7 +329,7 @@ func makeTypeHandler(server *_Server, t string, methods []Action) _HandlerFunc {
 				}
 			}
 
-			ret, err := server.Store.List(
+			ret, err := server.Store.(store.Pager).ListPage(
 				server.Context,
 				store.ObjectIdentity(
 					fmt.Sprintf("%s/", strings.ToLower(t))),
@@ -243,10 +338,22 @@ func makeTypeHandler(server *_Server, t string, methods []Action) _HandlerFunc {
 			if err != nil {
 				reportError(w, err, http.StatusBadRequest)
 				return
-			} else if ret != nil {
-				resp, _ := json.Marshal(ret)
-				writeResponse(w, resp)
 			}
+
+			if len(ret.Continue) > 0 {
+				w.Header().Set(ContinueHeader, ret.Continue)
+			}
+			if ret.Total != nil {
+				w.Header().Set(TotalHeader, strconv.Itoa(*ret.Total))
+			}
+
+			resp, err := marshalList(ret.Items, fields)
+			if err != nil {
+				reportError(w, err, http.StatusBadRequest)
+				return
+			}
+
+			writeResponse(w, resp)
 		case http.MethodPost:
 			data, err := utils.ReadStream(r.Body)
 			if err != nil {
@@ -273,49 +380,218 @@ func makeTypeHandler(server *_Server, t string, methods []Action) _HandlerFunc {
 	}
 }
 
+// fieldsArg parses the comma separated property paths of the projection
+func fieldsArg(vals url.Values) []string {
+	res := []string{}
+	for _, v := range vals[FieldsArg] {
+		for _, f := range strings.Split(v, ",") {
+			f = strings.TrimSpace(f)
+			if len(f) > 0 {
+				res = append(res, f)
+			}
+		}
+	}
+
+	return res
+}
+
+// filterOptions parses the filters shared by List and Watch
+func filterOptions(vals url.Values) ([]options.ListOption, []options.WatchOption, error) {
+	opts := []options.ListOption{}
+	watchOpts := []options.WatchOption{}
+
+	propFilter, ok := vals[PropFilterArg]
+	if ok {
+		flt := options.PropFilterSetting{}
+		err := json.Unmarshal([]byte(propFilter[0]), &flt)
+		if err != nil {
+			return nil, nil, err
+		}
+		opt := options.PropFilter(flt.Key, flt.Value)
+		opts = append(opts, opt)
+		watchOpts = append(watchOpts, opt)
+	}
+
+	keyFilter, ok := vals[KeyFilterArg]
+	if ok {
+		flt := options.KeyFilterSetting{}
+		err := json.Unmarshal([]byte(keyFilter[0]), &flt)
+		if err != nil {
+			return nil, nil, err
+		}
+		opt := options.KeyFilter(flt...)
+		opts = append(opts, opt)
+		watchOpts = append(watchOpts, opt)
+	}
+
+	exprFilter, ok := vals[FilterArg]
+	if ok {
+		expr := filter.Expression{}
+		err := json.Unmarshal([]byte(exprFilter[0]), &expr)
+		if err != nil {
+			return nil, nil, err
+		}
+		opt := options.Filter(&expr)
+		opts = append(opts, opt)
+		watchOpts = append(watchOpts, opt)
+	}
+
+	return opts, watchOpts, nil
+}
+
 func (d *_Server) handlePath(
 	w http.ResponseWriter,
 	r *http.Request,
 	identity store.ObjectIdentity,
 	object store.Object) {
 
+	rev, ok := d.preconditions(w, r, identity)
+	if !ok {
+		return
+	}
+
+	updateOpts := []options.UpdateOption{}
+	deleteOpts := []options.DeleteOption{}
+	if rev != nil {
+		updateOpts = append(updateOpts, options.IfRevision(*rev))
+		deleteOpts = append(deleteOpts, options.IfRevision(*rev))
+	}
+
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		err := store.Validate(object)
+		if err != nil {
+			reportError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// only gets are projected
+	fields := []string{}
+	getOpts := []options.GetOption{}
+	if r.Method == http.MethodGet {
+		fields = fieldsArg(r.URL.Query())
+	}
+	if len(fields) > 0 {
+		getOpts = append(getOpts, options.Fields(fields...))
+	}
+
 	var ret store.Object = nil
 	var err error = nil
 	switch r.Method {
 	case http.MethodGet:
-		ret, err = d.Store.Get(d.Context, identity)
+		ret, err = d.Store.Get(d.Context, identity, getOpts...)
 		if err != nil {
-			reportError(w, err, http.StatusNotFound)
+			reportError(w, err, http.StatusInternalServerError)
 			return
 		}
 	case http.MethodPost:
 		ret, err = d.Store.Create(d.Context, object)
 		if err != nil {
-			reportError(w, err, http.StatusNotAcceptable)
+			reportError(w, err, http.StatusBadRequest)
 			return
 		}
 	case http.MethodPut:
-		ret, err = d.Store.Update(d.Context, identity, object)
+		ret, err = d.Store.Update(d.Context, identity, object, updateOpts...)
 		if err != nil {
-			reportError(w, err, http.StatusNotAcceptable)
+			reportError(w, err, http.StatusBadRequest)
 			return
 		}
 	case http.MethodDelete:
-		err = d.Store.Delete(d.Context, identity)
+		err = d.Store.Delete(d.Context, identity, deleteOpts...)
 		if err != nil {
-			reportError(w, err, http.StatusNotFound)
+			reportError(w, err, http.StatusInternalServerError)
 			return
 		}
 	}
 
 	if err == nil && ret != nil {
-		resp, _ := json.Marshal(ret)
-		writeResponse(w, resp)
+		writeObject(w, r, ret, fields...)
+	}
+}
+
+// preconditions returns the revision the write requires, either given
+// or matched by If-Match, reporting the error when it cannot be met
+func (d *_Server) preconditions(
+	w http.ResponseWriter,
+	r *http.Request,
+	identity store.ObjectIdentity) (*int64, bool) {
+
+	var rev *int64
+	revision, ok := r.URL.Query()[RevisionArg]
+	if ok {
+		parsed, err := strconv.ParseInt(revision[0], 10, 64)
+		if err != nil {
+			reportError(w, err, http.StatusBadRequest)
+			return nil, false
+		}
+		rev = &parsed
 	}
+
+	if r.Method == http.MethodGet || r.Method == http.MethodPost {
+		return rev, true
+	}
+
+	// the matched revision guards the write unless one is given
+	matched, err := d.ifMatch(r, identity)
+	if err != nil {
+		reportError(w, err, http.StatusPreconditionFailed)
+		return nil, false
+	}
+	if rev == nil {
+		rev = matched
+	}
+
+	return rev, true
 }
 
-func reportError(w http.ResponseWriter, err error, code int) {
-	http.Error(w, err.Error(), code)
+// writeObject replies the object, or its projection to the fields
+// with the ETag of the projection
+func writeObject(w http.ResponseWriter, r *http.Request, obj store.Object, fields ...string) {
+	resp, err := marshalObject(obj, fields)
+	if err != nil {
+		reportError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	etag := etagOf(resp)
+	if r.Method == http.MethodGet && notModified(w, r, etag) {
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	writeResponse(w, resp)
+}
+
+// marshalObject leaves the properties out of the fields, projected
+// objects still hold the defaults of the unselected ones
+func marshalObject(obj store.Object, fields []string) ([]byte, error) {
+	if len(fields) == 0 {
+		return json.Marshal(obj)
+	}
+
+	doc, err := store.Projection(obj, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(doc)
+}
+
+func marshalList(list store.ObjectList, fields []string) ([]byte, error) {
+	if len(fields) == 0 {
+		return json.Marshal(list)
+	}
+
+	docs := []interface{}{}
+	for _, o := range list {
+		doc, err := store.Projection(o, fields)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	return json.Marshal(docs)
 }
 
 func writeResponse(w http.ResponseWriter, data []byte) {
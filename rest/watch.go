@@ -0,0 +1,78 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/store"
+	"github.com/wazofski/gostorz/store/options"
+)
+
+// KeepAlive is the interval of the comments keeping idle streams open
+var KeepAlive = 15 * time.Second
+
+// handleWatch streams the events of the kind as Server-Sent Events,
+// the event name is the event type and the data the object
+func (d *_Server) handleWatch(
+	w http.ResponseWriter,
+	r *http.Request,
+	kind string,
+	opts []options.WatchOption) {
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		reportError(w, constants.ErrNotSupported, http.StatusInternalServerError)
+		return
+	}
+
+	watcher, ok := d.Store.(store.Watcher)
+	if !ok {
+		reportError(w, constants.ErrNotSupported, http.StatusNotImplemented)
+		return
+	}
+
+	events, err := watcher.Watch(r.Context(),
+		store.ObjectIdentity(fmt.Sprintf("%s/", strings.ToLower(kind))),
+		opts...)
+
+	if err != nil {
+		reportError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(KeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case ev, ok := <-events:
+			// the store closes lagging watchers which have to re-List
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(ev.Object)
+			if err != nil {
+				log.Printf("watch %s: %s", kind, err)
+				continue
+			}
+
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+			flusher.Flush()
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package route_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/memory"
+	"github.com/wazofski/gostorz/route"
+	"github.com/wazofski/gostorz/store"
+)
+
+func TestRoute(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Route Suite")
+}
+
+var worlds store.Store
+var secondWorlds store.Store
+var others store.Store
+
+var routed store.Store
+var strict store.Store
+
+var ctx = context.Background()
+
+var _ = BeforeSuite(func() {
+	sch := generated.Schema()
+
+	worlds = store.New(sch, memory.Factory())
+	secondWorlds = store.New(sch, memory.Factory())
+	others = store.New(sch, memory.Factory())
+
+	routed = store.New(sch,
+		route.Factory(others,
+			route.Mapping{Kind: generated.WorldKind(), Store: worlds},
+			route.Mapping{Kind: generated.SecondWorldKind(), Store: secondWorlds}))
+
+	strict = store.New(sch,
+		route.Factory(nil,
+			route.Mapping{Kind: generated.WorldKind(), Store: worlds}))
+})
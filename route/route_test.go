@@ -0,0 +1,203 @@
+package route_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/memory"
+	"github.com/wazofski/gostorz/route"
+	"github.com/wazofski/gostorz/store"
+	"github.com/wazofski/gostorz/store/options"
+)
+
+var _ = Describe("route", func() {
+
+	worldId := store.ObjectIdentity("")
+
+	It("can route CREATE by kind", func() {
+		world := generated.WorldFactory()
+		world.External().SetName("abc")
+		ret, err := routed.Create(ctx, world)
+		Expect(err).To(BeNil())
+		worldId = ret.Metadata().Identity()
+
+		second := generated.SecondWorldFactory()
+		second.External().SetName("abc")
+		_, err = routed.Create(ctx, second)
+		Expect(err).To(BeNil())
+
+		third := generated.ThirdWorldFactory()
+		third.External().SetName("abc")
+		_, err = routed.Create(ctx, third)
+		Expect(err).To(BeNil())
+
+		_, err = worlds.Get(ctx, generated.WorldIdentity("abc"))
+		Expect(err).To(BeNil())
+		_, err = secondWorlds.Get(ctx, generated.SecondWorldIdentity("abc"))
+		Expect(err).To(BeNil())
+		_, err = others.Get(ctx, generated.ThirdWorldIdentity("abc"))
+		Expect(err).To(BeNil())
+
+		_, err = others.Get(ctx, generated.WorldIdentity("abc"))
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("can GET, UPDATE and DELETE by id", func() {
+		ret, err := routed.Get(ctx, worldId)
+		Expect(err).To(BeNil())
+		Expect(ret.PrimaryKey()).To(Equal("abc"))
+
+		world := ret.(generated.World)
+		world.External().SetDescription("def")
+		_, err = routed.Update(ctx, worldId, world)
+		Expect(err).To(BeNil())
+
+		ret, err = worlds.Get(ctx, worldId)
+		Expect(err).To(BeNil())
+		Expect(ret.(generated.World).External().Description()).To(Equal("def"))
+
+		err = routed.Delete(ctx, worldId)
+		Expect(err).To(BeNil())
+
+		_, err = routed.Get(ctx, worldId)
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("can LIST by kind", func() {
+		ret, err := routed.List(ctx, generated.SecondWorldKindIdentity())
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(1))
+		Expect(ret[0].Metadata().Kind()).To(Equal(generated.SecondWorldKind()))
+	})
+
+	It("can fan out LIST across kinds", func() {
+		ret, err := routed.List(ctx, "",
+			options.PropFilter("external.name", "abc"),
+			options.OrderBy("metadata.kind"))
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(2))
+		Expect(ret[0].Metadata().Kind()).To(Equal(generated.SecondWorldKind()))
+		Expect(ret[1].Metadata().Kind()).To(Equal(generated.ThirdWorldKind()))
+
+		_, err = routed.List(ctx, "", options.PageSize(1))
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("can fan out LIST ordered by numbers", func() {
+		for i, counter := range []int{10, 9, 100} {
+			second := generated.SecondWorldFactory()
+			second.External().SetName(fmt.Sprintf("a-%d", i))
+			second.External().SetDescription("counted")
+			second.External().Nested().SetCounter(counter)
+			_, err := routed.Create(ctx, second)
+			Expect(err).To(BeNil())
+
+			third := generated.ThirdWorldFactory()
+			third.External().SetName(fmt.Sprintf("b-%d", i))
+			third.External().SetDescription("counted")
+			third.External().Nested().SetCounter(counter)
+			_, err = routed.Create(ctx, third)
+			Expect(err).To(BeNil())
+		}
+
+		ret, err := routed.List(ctx, "",
+			options.PropFilter("external.description", "counted"),
+			options.OrderBy("external.nested.counter"))
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(6))
+
+		names := []string{}
+		for _, o := range ret {
+			names = append(names, fmt.Sprintf("%s/%s", o.Metadata().Kind(), o.PrimaryKey()))
+		}
+		Expect(names).To(Equal([]string{
+			"SecondWorld/a-1", "ThirdWorld/b-1",
+			"SecondWorld/a-0", "ThirdWorld/b-0",
+			"SecondWorld/a-2", "ThirdWorld/b-2",
+		}))
+	})
+
+	It("can report backend failures of id lookups", func() {
+		sch := generated.Schema()
+		healthy := store.New(sch, memory.Factory())
+		failing := store.New(sch,
+			route.Factory(healthy,
+				route.Mapping{Kind: generated.WorldKind(), Store: unavailableStore{healthy}}))
+
+		second := generated.SecondWorldFactory()
+		second.External().SetName("found")
+		ret, err := healthy.Create(ctx, second)
+		Expect(err).To(BeNil())
+
+		_, err = failing.Get(ctx, ret.Metadata().Identity())
+		Expect(err).To(BeNil())
+
+		missing := store.ObjectIdentity("id/missing")
+		_, err = failing.Get(ctx, missing)
+		Expect(err).To(Equal(errUnavailable))
+
+		err = failing.Delete(ctx, missing)
+		Expect(err).To(Equal(errUnavailable))
+
+		_, err = routed.Get(ctx, missing)
+		Expect(errors.Is(err, constants.ErrNoSuchObject)).To(BeTrue())
+	})
+
+	It("can run transactions on a single store", func() {
+		sch := generated.Schema()
+		single := store.New(sch, memory.Factory())
+		mapped := store.New(sch,
+			route.Factory(single,
+				route.Mapping{Kind: generated.WorldKind(), Store: single}))
+
+		err := mapped.(store.Transactional).Txn(ctx, func(tx store.Store) error {
+			world := generated.WorldFactory()
+			world.External().SetName("transacted")
+			_, err := tx.Create(ctx, world)
+			if err != nil {
+				return err
+			}
+
+			return errors.New("rollback")
+		})
+		Expect(err).ToNot(BeNil())
+
+		_, err = single.Get(ctx, generated.WorldIdentity("transacted"))
+		Expect(errors.Is(err, constants.ErrNoSuchObject)).To(BeTrue())
+
+		err = routed.(store.Transactional).Txn(ctx, func(tx store.Store) error {
+			return nil
+		})
+		Expect(errors.Is(err, constants.ErrNotSupported)).To(BeTrue())
+	})
+
+	It("can reject unknown kinds", func() {
+		second := generated.SecondWorldFactory()
+		second.External().SetName("xyz")
+		_, err := strict.Create(ctx, second)
+		Expect(errors.Is(err, constants.ErrUnknownKind)).To(BeTrue())
+
+		_, err = strict.List(ctx, generated.SecondWorldKindIdentity())
+		Expect(errors.Is(err, constants.ErrUnknownKind)).To(BeTrue())
+	})
+})
+
+var errUnavailable = errors.New("store unavailable")
+
+// unavailableStore fails every read
+type unavailableStore struct {
+	store.Store
+}
+
+func (unavailableStore) Get(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.GetOption) (store.Object, error) {
+
+	return nil, errUnavailable
+}
@@ -2,11 +2,16 @@ package route
 
 import (
 	"context"
+	"errors"
+	"sort"
+	"strings"
 
+	"github.com/wazofski/gostorz/filter"
 	"github.com/wazofski/gostorz/internal/constants"
 	"github.com/wazofski/gostorz/internal/logger"
 	"github.com/wazofski/gostorz/store"
 	"github.com/wazofski/gostorz/store/options"
+	"github.com/wazofski/gostorz/utils"
 )
 
 type routeStore struct {
@@ -21,6 +26,9 @@ type Mapping struct {
 	Store store.Store
 }
 
+// Factory routes every kind to its mapped store.
+// Unmapped kinds go to the default store, or fail
+// with constants.ErrUnknownKind when the default is nil.
 func Factory(deault store.Store, mappings ...Mapping) store.Factory {
 	return func(schema store.SchemaHolder) (store.Store, error) {
 		client := &routeStore{
@@ -31,7 +39,7 @@ func Factory(deault store.Store, mappings ...Mapping) store.Factory {
 		}
 
 		for _, m := range mappings {
-			client.Mapping[m.Kind] = m.Store
+			client.Mapping[strings.ToLower(m.Kind)] = m.Store
 		}
 
 		return client, nil
@@ -49,7 +57,12 @@ func (d *routeStore) Create(
 
 	d.Log.Printf("create %s", obj.PrimaryKey())
 
-	return d.Default.Create(ctx, obj, opt...)
+	st, err := d.route(obj.Metadata().Kind())
+	if err != nil {
+		return nil, err
+	}
+
+	return st.Create(ctx, obj, opt...)
 }
 
 func (d *routeStore) Update(
@@ -64,7 +77,33 @@ func (d *routeStore) Update(
 
 	d.Log.Printf("update %s", identity.Path())
 
-	return d.Default.Update(ctx, identity, obj, opt...)
+	st, err := d.locate(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return st.Update(ctx, identity, obj, opt...)
+}
+
+func (d *routeStore) Patch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	patch store.Patch,
+	opt ...options.UpdateOption) (store.Object, error) {
+
+	d.Log.Printf("patch %s", identity.Path())
+
+	st, err := d.locate(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	patcher, ok := st.(store.Patcher)
+	if !ok {
+		return nil, constants.ErrNotSupported
+	}
+
+	return patcher.Patch(ctx, identity, patch, opt...)
 }
 
 func (d *routeStore) Delete(
@@ -74,7 +113,12 @@ func (d *routeStore) Delete(
 
 	d.Log.Printf("delete %s", identity.Path())
 
-	return d.Default.Delete(ctx, identity, opt...)
+	st, err := d.locate(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	return st.Delete(ctx, identity, opt...)
 }
 
 func (d *routeStore) Get(
@@ -84,7 +128,27 @@ func (d *routeStore) Get(
 
 	d.Log.Printf("get %s", identity.Path())
 
-	return d.Default.Get(ctx, identity, opt...)
+	if identity.Type() == "id" {
+		var res error = constants.ErrNoSuchObject
+		for _, st := range d.stores() {
+			ret, err := st.Get(ctx, identity, opt...)
+			if err == nil && ret != nil {
+				return ret, nil
+			}
+			if probeFailed(res, err) {
+				res = err
+			}
+		}
+
+		return nil, res
+	}
+
+	st, err := d.route(identity.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	return st.Get(ctx, identity, opt...)
 }
 
 func (d *routeStore) List(
@@ -94,5 +158,272 @@ func (d *routeStore) List(
 
 	d.Log.Printf("list %s", identity.Type())
 
-	return d.Default.List(ctx, identity, opt...)
+	if identity.Type() != "id" {
+		st, err := d.route(identity.Type())
+		if err != nil {
+			return nil, err
+		}
+
+		return st.List(ctx, identity, opt...)
+	}
+
+	if len(identity.Key()) > 0 {
+		return nil, constants.ErrInvalidPath
+	}
+
+	// fan out to every known kind
+	copt := options.CommonOptionHolderFactory()
+	for _, o := range opt {
+		err := o.ApplyFunction()(&copt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// merged listings of several kinds can neither be paged nor projected
+	if copt.PageSize > 0 || copt.PageOffset > 0 || len(copt.Continue) > 0 ||
+		len(copt.Fields) > 0 {
+		return nil, constants.ErrNotSupported
+	}
+
+	res := store.ObjectList{}
+	for _, kind := range d.Schema.Types() {
+		st, err := d.route(kind)
+		if err != nil {
+			continue
+		}
+
+		ret, err := st.List(ctx,
+			store.ObjectIdentity(strings.ToLower(kind)+"/"), opt...)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, ret...)
+	}
+
+	// merged results order like the memory store, by the
+	// order values the way the filters compare them and
+	// then by the primary keys
+	if len(copt.OrderBy) > 0 {
+		values := make(map[store.Object]interface{}, len(res))
+		for _, o := range res {
+			values[o] = utils.ObjectValue(o, copt.OrderBy)
+		}
+
+		sort.Slice(res, func(p, q int) bool {
+			cmp, _ := filter.Compare(values[res[p]], values[res[q]])
+			if cmp == 0 {
+				cmp = strings.Compare(res[p].PrimaryKey(), res[q].PrimaryKey())
+			}
+
+			if copt.OrderIncremental {
+				return cmp < 0
+			}
+			return cmp > 0
+		})
+	}
+
+	return res, nil
+}
+
+// ListPage pages through the kind of a single store,
+// id listings fan out to several stores and cannot be paged
+func (d *routeStore) ListPage(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.ListOption) (*store.Page, error) {
+
+	d.Log.Printf("list page %s", identity.Type())
+
+	if identity.Type() == "id" {
+		return nil, constants.ErrNotSupported
+	}
+
+	st, err := d.route(identity.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	pager, ok := st.(store.Pager)
+	if !ok {
+		return nil, constants.ErrNotSupported
+	}
+
+	return pager.ListPage(ctx, identity, opt...)
+}
+
+func (d *routeStore) Count(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.ListOption) (int, error) {
+
+	d.Log.Printf("count %s", identity.Type())
+
+	if identity.Type() == "id" {
+		return 0, constants.ErrNotSupported
+	}
+
+	st, err := d.route(identity.Type())
+	if err != nil {
+		return 0, err
+	}
+
+	return store.Count(ctx, st, identity, opt...)
+}
+
+func (d *routeStore) Aggregate(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	agg store.Aggregation,
+	opt ...options.ListOption) ([]store.AggregateResult, error) {
+
+	d.Log.Printf("aggregate %s", identity.Type())
+
+	if identity.Type() == "id" {
+		return nil, constants.ErrNotSupported
+	}
+
+	st, err := d.route(identity.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	return store.AggregateList(ctx, st, identity, agg, opt...)
+}
+
+func (d *routeStore) Watch(
+	ctx context.Context,
+	identity store.ObjectIdentity,
+	opt ...options.WatchOption) (<-chan store.Event, error) {
+
+	d.Log.Printf("watch %s", identity.Path())
+
+	if identity.Type() == "id" && len(identity.Key()) == 0 {
+		return nil, constants.ErrInvalidPath
+	}
+
+	st, err := d.locate(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, ok := st.(store.Watcher)
+	if !ok {
+		return nil, constants.ErrNotSupported
+	}
+
+	return watcher.Watch(ctx, identity, opt...)
+}
+
+func (d *routeStore) route(kind string) (store.Store, error) {
+	st, ok := d.Mapping[strings.ToLower(kind)]
+	if ok {
+		return st, nil
+	}
+
+	if d.Default == nil {
+		return nil, constants.ErrUnknownKind
+	}
+
+	return d.Default, nil
+}
+
+// locate finds the store holding the identity,
+// probing every store for id/<uuid> lookups
+func (d *routeStore) locate(
+	ctx context.Context,
+	identity store.ObjectIdentity) (store.Store, error) {
+
+	if identity.Type() != "id" {
+		return d.route(identity.Type())
+	}
+
+	var res error = constants.ErrNoSuchObject
+	for _, st := range d.stores() {
+		ret, err := st.Get(ctx, identity)
+		if err == nil && ret != nil {
+			return st, nil
+		}
+		if probeFailed(res, err) {
+			res = err
+		}
+	}
+
+	return nil, res
+}
+
+// probeFailed tells whether the error of a backend probe replaces
+// the one so far, the first failure other than ErrNoSuchObject wins
+func probeFailed(res error, err error) bool {
+	return err != nil &&
+		errors.Is(res, constants.ErrNoSuchObject) &&
+		!errors.Is(err, constants.ErrNoSuchObject)
+}
+
+// Txn runs the function in a transaction of the backing store when
+// every kind routes to the same transactional store, transactions
+// cannot span several stores
+func (d *routeStore) Txn(
+	ctx context.Context,
+	fn func(store.Store) error) error {
+
+	d.Log.Printf("txn")
+
+	stores := d.stores()
+	if len(stores) != 1 {
+		return constants.ErrNotSupported
+	}
+
+	transactional, ok := stores[0].(store.Transactional)
+	if !ok {
+		return constants.ErrNotSupported
+	}
+
+	return transactional.Txn(ctx, func(tx store.Store) error {
+		client := &routeStore{
+			Schema:  d.Schema,
+			Log:     d.Log,
+			Mapping: make(map[string]store.Store),
+		}
+
+		for k := range d.Mapping {
+			client.Mapping[k] = tx
+		}
+
+		if d.Default != nil {
+			client.Default = tx
+		}
+
+		return fn(client)
+	})
+}
+
+// stores lists every distinct store, mapped ones first
+func (d *routeStore) stores() []store.Store {
+	kinds := []string{}
+	for k := range d.Mapping {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+
+	res := []store.Store{}
+	add := func(st store.Store) {
+		for _, s := range res {
+			if s == st {
+				return
+			}
+		}
+		res = append(res, st)
+	}
+
+	for _, k := range kinds {
+		add(d.Mapping[k])
+	}
+
+	if d.Default != nil {
+		add(d.Default)
+	}
+
+	return res
 }
@@ -0,0 +1,203 @@
+package sql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// _Dialect generates the database specific parts of the queries.
+// Queries are written with ? placeholders and passed through Rebind.
+type _Dialect interface {
+	Tables() []string
+	Path(key string) (string, []interface{})
+	JSON(key string) (string, []interface{})
+	JSONObject(values []string) string
+	Value(value interface{}) interface{}
+	Number(col string) string
+	Revision() string
+	UpsertIdentity() string
+	UpsertObject() string
+	Rebind(query string) string
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Tables() []string {
+	return []string{`
+		CREATE TABLE IF NOT EXISTS IdIndex (
+		Path VARCHAR(25) NOT NULL PRIMARY KEY,
+		Pkey NVARCHAR(50) NOT NULL,
+		Type VARCHAR(25) NOT NULL);`, `
+		CREATE TABLE IF NOT EXISTS Objects (
+		Pkey NVARCHAR(50) NOT NULL,
+		Type VARCHAR(25) NOT NULL,
+		Object JSON,
+		PRIMARY KEY (Pkey,Type));`,
+	}
+}
+
+func (sqliteDialect) Path(key string) (string, []interface{}) {
+	return "json_extract(Object, ?)", []interface{}{"$." + key}
+}
+
+// JSON selects the property as JSON, keeping booleans apart from numbers
+func (sqliteDialect) JSON(key string) (string, []interface{}) {
+	return "(Object -> ?)", []interface{}{"$." + key}
+}
+
+// JSONObject builds an object of the values, keyed by
+// the placeholder preceding each of them
+func (sqliteDialect) JSONObject(values []string) string {
+	members := []string{}
+	for _, v := range values {
+		members = append(members, "?, "+v)
+	}
+
+	return fmt.Sprintf("json_object(%s)", strings.Join(members, ", "))
+}
+
+// Value compares with json_extract as the JSON value itself
+func (sqliteDialect) Value(value interface{}) interface{} {
+	return value
+}
+
+// Number keeps the numbers json_extract returns
+func (sqliteDialect) Number(col string) string {
+	return col
+}
+
+func (sqliteDialect) Revision() string {
+	return "COALESCE(json_extract(Object, '$.metadata.revision'), 0)"
+}
+
+func (sqliteDialect) UpsertIdentity() string {
+	return `INSERT INTO IdIndex (Path, Pkey, Type) VALUES (?, ?, ?)
+		ON CONFLICT (Path) DO UPDATE SET Pkey = excluded.Pkey, Type = excluded.Type`
+}
+
+func (sqliteDialect) UpsertObject() string {
+	return `INSERT INTO Objects (Pkey, Type, Object) VALUES (?, ?, ?)
+		ON CONFLICT (Pkey, Type) DO UPDATE SET Object = excluded.Object`
+}
+
+func (sqliteDialect) Rebind(query string) string {
+	return query
+}
+
+type mysqlDialect struct {
+	sqliteDialect
+}
+
+// JSON extracts the property, -> takes no placeholders in mysql
+func (mysqlDialect) JSON(key string) (string, []interface{}) {
+	return "JSON_EXTRACT(Object, ?)", []interface{}{"$." + key}
+}
+
+func (mysqlDialect) UpsertIdentity() string {
+	return `INSERT INTO IdIndex (Path, Pkey, Type) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE Pkey = VALUES(Pkey), Type = VALUES(Type)`
+}
+
+func (mysqlDialect) UpsertObject() string {
+	return `INSERT INTO Objects (Pkey, Type, Object) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE Object = VALUES(Object)`
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Tables() []string {
+	return []string{`
+		CREATE TABLE IF NOT EXISTS IdIndex (
+		Path VARCHAR(64) NOT NULL PRIMARY KEY,
+		Pkey VARCHAR(50) NOT NULL,
+		Type VARCHAR(25) NOT NULL);`, `
+		CREATE TABLE IF NOT EXISTS Objects (
+		Pkey VARCHAR(50) NOT NULL,
+		Type VARCHAR(25) NOT NULL,
+		Object JSONB,
+		PRIMARY KEY (Pkey,Type));`,
+	}
+}
+
+// Path walks the nested keys with -> and extracts the leaf as text
+func (postgresDialect) Path(key string) (string, []interface{}) {
+	tokens := strings.Split(key, ".")
+	args := []interface{}{}
+	for _, t := range tokens {
+		args = append(args, t)
+	}
+
+	return "(Object" + strings.Repeat("->?", len(tokens)-1) + "->>?)", args
+}
+
+// JSON walks the nested keys with -> keeping the JSONB value
+func (postgresDialect) JSON(key string) (string, []interface{}) {
+	tokens := strings.Split(key, ".")
+	args := []interface{}{}
+	for _, t := range tokens {
+		args = append(args, t)
+	}
+
+	return "(Object" + strings.Repeat("->?", len(tokens)) + ")", args
+}
+
+// JSONObject builds an object of the values, the key
+// placeholders are cast as their type cannot be inferred
+func (postgresDialect) JSONObject(values []string) string {
+	members := []string{}
+	for _, v := range values {
+		members = append(members, "?::TEXT, "+v)
+	}
+
+	return fmt.Sprintf("jsonb_build_object(%s)", strings.Join(members, ", "))
+}
+
+// Value compares with ->> as the text of the JSON value
+func (postgresDialect) Value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return v
+	}
+
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// Number casts the text of ->> to compare and sum numerically
+func (postgresDialect) Number(col string) string {
+	return fmt.Sprintf("(%s)::NUMERIC", col)
+}
+
+func (postgresDialect) Revision() string {
+	return "COALESCE((Object->'metadata'->>'revision')::BIGINT, 0)"
+}
+
+func (postgresDialect) UpsertIdentity() string {
+	return `INSERT INTO IdIndex (Path, Pkey, Type) VALUES (?, ?, ?)
+		ON CONFLICT (Path) DO UPDATE SET Pkey = EXCLUDED.Pkey, Type = EXCLUDED.Type`
+}
+
+func (postgresDialect) UpsertObject() string {
+	return `INSERT INTO Objects (Pkey, Type, Object) VALUES (?, ?, ?)
+		ON CONFLICT (Pkey, Type) DO UPDATE SET Object = EXCLUDED.Object`
+}
+
+// Rebind numbers the ? placeholders as $1, $2...
+func (postgresDialect) Rebind(query string) string {
+	b := strings.Builder{}
+	n := 0
+	for _, c := range query {
+		if c != '?' {
+			b.WriteRune(c)
+			continue
+		}
+
+		n++
+		b.WriteString(fmt.Sprintf("$%d", n))
+	}
+
+	return b.String()
+}
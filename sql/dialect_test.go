@@ -0,0 +1,276 @@
+package sql
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/wazofski/gostorz/filter"
+	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/internal/cursor"
+	"github.com/wazofski/gostorz/store"
+	"github.com/wazofski/gostorz/store/options"
+)
+
+var _ = Describe("dialect", func() {
+
+	postgres := &sqlStore{
+		Schema:  generated.Schema(),
+		Dialect: postgresDialect{},
+	}
+
+	sqlite := &sqlStore{
+		Schema:  generated.Schema(),
+		Dialect: sqliteDialect{},
+	}
+
+	listOptions := func(opt ...options.ListOption) options.CommonOptionHolder {
+		copt := options.CommonOptionHolderFactory()
+		for _, o := range opt {
+			Expect(o.ApplyFunction()(&copt)).To(Succeed())
+		}
+		return copt
+	}
+
+	It("can rebind postgres placeholders", func() {
+		Expect(postgresDialect{}.Rebind("a = ? AND b IN (?, ?)")).
+			To(Equal("a = $1 AND b IN ($2, $3)"))
+		Expect(sqliteDialect{}.Rebind("a = ?")).To(Equal("a = ?"))
+	})
+
+	It("can generate postgres tables", func() {
+		tables := postgresDialect{}.Tables()
+		Expect(len(tables)).To(Equal(2))
+		Expect(tables[1]).To(ContainSubstring("Object JSONB"))
+	})
+
+	It("can generate postgres upserts", func() {
+		Expect(postgres.Dialect.Rebind(postgres.Dialect.UpsertObject())).
+			To(Equal(`INSERT INTO Objects (Pkey, Type, Object) VALUES ($1, $2, $3)
+		ON CONFLICT (Pkey, Type) DO UPDATE SET Object = EXCLUDED.Object`))
+		Expect(mysqlDialect{}.UpsertObject()).
+			To(ContainSubstring("ON DUPLICATE KEY UPDATE"))
+	})
+
+	It("can generate postgres List queries", func() {
+		query, args, err := postgres.listQuery(
+			generated.WorldKindIdentity(),
+			listOptions(
+				options.KeyFilter("abc", "def"),
+				options.PropFilter("external.name", "abc"),
+				options.Filter(filter.Or(
+					filter.Eq("external.description", "x"),
+					filter.In("metadata.kind", "World"))),
+				options.OrderBy("external.name"),
+				options.PageSize(5)))
+
+		Expect(err).To(BeNil())
+		Expect(query).To(Equal(`SELECT Pkey, Object FROM Objects
+		WHERE Type = $1` +
+			` AND Pkey IN ($2, $3)` +
+			` AND (Object->$4->>$5) = $6` +
+			` AND (((Object->$7->>$8) = $9) OR ((Object->$10->>$11) IN ($12)))` +
+			` ORDER BY (Object->$13->>$14) ASC, Pkey ASC LIMIT 6`))
+		Expect(args).To(Equal([]interface{}{
+			"world", "abc", "def",
+			"external", "name", "abc",
+			"external", "description", "x",
+			"metadata", "kind", "World",
+			"external", "name"}))
+	})
+
+	It("can generate postgres numeric comparisons", func() {
+		query, args, err := postgres.listQuery(
+			generated.WorldKindIdentity(),
+			listOptions(
+				options.PropFilter("external.nested.counter", "10"),
+				options.Filter(filter.Gt("external.nested.counter", 5)),
+				options.OrderBy("external.nested.counter")))
+
+		Expect(err).To(BeNil())
+		Expect(query).To(Equal(`SELECT Pkey, Object FROM Objects
+		WHERE Type = $1` +
+			` AND ((Object->$2->$3->>$4))::NUMERIC = $5` +
+			` AND (((Object->$6->$7->>$8))::NUMERIC > $9)` +
+			` ORDER BY ((Object->$10->$11->>$12))::NUMERIC ASC, Pkey ASC`))
+		Expect(args).To(Equal([]interface{}{
+			"world",
+			"external", "nested", "counter", "10",
+			"external", "nested", "counter", 5,
+			"external", "nested", "counter"}))
+	})
+
+	It("can generate sqlite List queries", func() {
+		query, args, err := sqlite.listQuery(
+			generated.WorldKindIdentity(),
+			listOptions(
+				options.PropFilter("external.name", "abc"),
+				options.OrderBy("external.name"),
+				options.OrderDescending()))
+
+		Expect(err).To(BeNil())
+		Expect(query).To(Equal(`SELECT Pkey, Object FROM Objects
+		WHERE Type = ?` +
+			` AND json_extract(Object, ?) = ?` +
+			` ORDER BY json_extract(Object, ?) DESC, Pkey DESC`))
+		Expect(args).To(Equal([]interface{}{
+			"world", "$.external.name", "abc", "$.external.name"}))
+	})
+
+	It("can generate continued List queries", func() {
+		token := cursor.Cursor{
+			Kind:    "world",
+			OrderBy: "external.nested.counter",
+			Value:   5.0,
+			Pkey:    "abc",
+		}.Token()
+
+		query, args, err := postgres.listQuery(
+			generated.WorldKindIdentity(),
+			listOptions(
+				options.OrderBy("external.nested.counter"),
+				options.Continue(token),
+				options.PageSize(2)))
+
+		Expect(err).To(BeNil())
+		Expect(query).To(Equal(`SELECT Pkey, Object FROM Objects
+		WHERE Type = $1` +
+			` AND (((Object->$2->$3->>$4))::NUMERIC > $5` +
+			` OR (((Object->$6->$7->>$8))::NUMERIC = $9 AND Pkey > $10))` +
+			` ORDER BY ((Object->$11->$12->>$13))::NUMERIC ASC, Pkey ASC LIMIT 3`))
+		Expect(args).To(Equal([]interface{}{
+			"world",
+			"external", "nested", "counter", "5",
+			"external", "nested", "counter", "5", "abc",
+			"external", "nested", "counter"}))
+
+		query, args, err = sqlite.listQuery(
+			generated.WorldKindIdentity(),
+			listOptions(options.Continue(cursor.Cursor{
+				Kind: "world",
+				Pkey: "abc",
+			}.Token())))
+
+		Expect(err).To(BeNil())
+		Expect(query).To(Equal(`SELECT Pkey, Object FROM Objects
+		WHERE Type = ? AND Pkey > ? ORDER BY Pkey ASC`))
+		Expect(args).To(Equal([]interface{}{"world", "abc"}))
+
+		query, args, err = sqlite.countQuery(
+			generated.WorldKindIdentity(),
+			listOptions(
+				options.PropFilter("external.name", "abc"),
+				options.Continue(token),
+				options.PageSize(2)))
+
+		Expect(err).To(BeNil())
+		Expect(query).To(Equal(`SELECT COUNT(*) FROM Objects
+		WHERE Type = ? AND json_extract(Object, ?) = ?`))
+		Expect(args).To(Equal([]interface{}{"world", "$.external.name", "abc"}))
+	})
+
+	It("can reject foreign continue tokens", func() {
+		token := cursor.Cursor{Kind: "world", Pkey: "abc"}.Token()
+
+		_, _, err := sqlite.listQuery(
+			generated.WorldKindIdentity(),
+			listOptions(options.Continue(token), options.OrderBy("external.name")))
+		Expect(errors.Is(err, constants.ErrInvalidToken)).To(BeTrue())
+
+		_, _, err = sqlite.listQuery(
+			generated.SecondWorldKindIdentity(),
+			listOptions(options.Continue(token)))
+		Expect(errors.Is(err, constants.ErrInvalidToken)).To(BeTrue())
+
+		_, _, err = sqlite.listQuery(
+			generated.WorldKindIdentity(),
+			listOptions(options.Continue("garbage")))
+		Expect(errors.Is(err, constants.ErrInvalidToken)).To(BeTrue())
+	})
+
+	It("can generate projected queries", func() {
+		query, args, err := sqlite.listQuery(
+			generated.WorldKindIdentity(),
+			listOptions(
+				options.Fields("external.name", "external.nested.alive", "external"),
+				options.OrderBy("metadata.updated")))
+
+		Expect(err).To(BeNil())
+		Expect(query).To(Equal(`SELECT Pkey, json_object(` +
+			`?, (Object -> ?), ` +
+			`?, json_object(?, (Object -> ?), ?, (Object -> ?), ?, (Object -> ?))) FROM Objects
+		WHERE Type = ? ORDER BY json_extract(Object, ?) ASC, Pkey ASC`))
+		Expect(args).To(Equal([]interface{}{
+			"external", "$.external",
+			"metadata",
+			"identity", "$.metadata.identity",
+			"kind", "$.metadata.kind",
+			"updated", "$.metadata.updated",
+			"world", "$.metadata.updated"}))
+
+		column, args, err := postgres.projection("world", []string{"external.nested.counter"})
+		Expect(err).To(BeNil())
+		Expect(column).To(Equal(`jsonb_build_object(` +
+			`?::TEXT, jsonb_build_object(?::TEXT, jsonb_build_object(?::TEXT, (Object->?->?->?))), ` +
+			`?::TEXT, jsonb_build_object(?::TEXT, (Object->?->?), ?::TEXT, (Object->?->?)))`))
+		Expect(args).To(Equal([]interface{}{
+			"external", "nested", "counter", "external", "nested", "counter",
+			"metadata",
+			"identity", "metadata", "identity",
+			"kind", "metadata", "kind"}))
+
+		_, _, err = sqlite.listQuery(
+			generated.WorldKindIdentity(),
+			listOptions(options.Fields("external.nothing")))
+		Expect(errors.Is(err, constants.ErrInvalidFilter)).To(BeTrue())
+	})
+
+	It("can generate aggregate queries", func() {
+		query, args, err := postgres.aggregateQuery(
+			generated.WorldKindIdentity(),
+			listOptions(options.PropFilter("external.name", "abc")),
+			store.Aggregation{
+				Function: store.AggregateSum,
+				Property: "external.nested.counter",
+				GroupBy:  "external.description",
+			})
+
+		Expect(err).To(BeNil())
+		Expect(query).To(Equal(`SELECT COUNT(*), SUM(((Object->$1->$2->>$3))::NUMERIC), (Object->$4->>$5) FROM Objects
+		WHERE Type = $6 AND (Object->$7->>$8) = $9 GROUP BY 3 ORDER BY 3`))
+		Expect(args).To(Equal([]interface{}{
+			"external", "nested", "counter",
+			"external", "description",
+			"world", "external", "name", "abc"}))
+
+		query, args, err = sqlite.aggregateQuery(
+			generated.WorldKindIdentity(),
+			listOptions(),
+			store.Aggregation{Function: store.AggregateCount})
+
+		Expect(err).To(BeNil())
+		Expect(query).To(Equal(`SELECT COUNT(*), NULL, NULL FROM Objects
+		WHERE Type = ?`))
+		Expect(args).To(Equal([]interface{}{"world"}))
+
+		_, _, err = sqlite.aggregateQuery(
+			generated.WorldKindIdentity(),
+			listOptions(),
+			store.Aggregation{Function: store.AggregateSum, Property: "external.name"})
+		Expect(errors.Is(err, constants.ErrInvalidAggr)).To(BeTrue())
+
+		_, _, err = sqlite.aggregateQuery(
+			generated.WorldKindIdentity(),
+			listOptions(),
+			store.Aggregation{Function: store.AggregateMax, Property: "external.nested"})
+		Expect(errors.Is(err, constants.ErrInvalidAggr)).To(BeTrue())
+	})
+
+	It("can reject unknown keys", func() {
+		_, _, err := postgres.listQuery(
+			generated.WorldKindIdentity(),
+			listOptions(options.OrderBy("external.nothing")))
+		Expect(err).ToNot(BeNil())
+	})
+})
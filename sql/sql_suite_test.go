@@ -0,0 +1,62 @@
+package sql_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/wazofski/gostorz/client"
+	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/rest"
+	"github.com/wazofski/gostorz/sql"
+	"github.com/wazofski/gostorz/store"
+)
+
+func TestSql(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "sql suite")
+}
+
+var sqlite store.Store
+var stc store.Store
+var ctx = context.Background()
+var cancel context.CancelFunc
+var dir string
+
+var _ = BeforeSuite(func() {
+	sch := generated.Schema()
+
+	var err error
+	dir, err = os.MkdirTemp("", "gostorz")
+	Expect(err).To(BeNil())
+
+	sqlite = store.New(sch,
+		sql.Factory(sql.SqliteConnection(filepath.Join(dir, "test.sqlite"))))
+
+	srv := rest.Server(sch, sqlite,
+		rest.TypeMethods(generated.WorldKind(),
+			rest.ActionGet, rest.ActionCreate))
+
+	cancel = srv.Listen(8002)
+	Eventually(func() error {
+		conn, err := net.Dial("tcp", "localhost:8002")
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}).Should(Succeed())
+
+	stc = store.New(sch, client.Factory("http://localhost:8002/"))
+})
+
+var _ = AfterSuite(func() {
+	if cancel != nil {
+		cancel()
+	}
+
+	os.RemoveAll(dir)
+})
@@ -0,0 +1,76 @@
+package sql_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/wazofski/gostorz/filter"
+	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/store/options"
+)
+
+var _ = Describe("sql", func() {
+
+	hostile := "x' OR '1'='1"
+
+	It("can create worlds through the server", func() {
+		for _, name := range []string{"abc", "def", hostile} {
+			world := generated.WorldFactory()
+			world.External().SetName(name)
+			_, err := stc.Create(ctx, world)
+			Expect(err).To(BeNil())
+		}
+	})
+
+	It("can bind hostile PropFilter values", func() {
+		ret, err := stc.List(ctx, generated.WorldKindIdentity(),
+			options.PropFilter("external.name", "abc' OR '1'='1"))
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(0))
+
+		ret, err = stc.List(ctx, generated.WorldKindIdentity(),
+			options.PropFilter("external.name", hostile))
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(1))
+		Expect(ret[0].PrimaryKey()).To(Equal(hostile))
+	})
+
+	It("can reject hostile PropFilter keys", func() {
+		_, err := stc.List(ctx, generated.WorldKindIdentity(),
+			options.PropFilter("external.name') = json_extract(Object, '$.external.name", "abc"))
+		Expect(err).ToNot(BeNil())
+
+		_, err = stc.List(ctx, generated.WorldKindIdentity(),
+			options.PropFilter("external.name') OR 1=1 --", "abc"))
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("can bind hostile KeyFilter values", func() {
+		ret, err := stc.List(ctx, generated.WorldKindIdentity(),
+			options.KeyFilter("abc') OR ('1'='1"))
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(0))
+
+		ret, err = stc.List(ctx, generated.WorldKindIdentity(),
+			options.KeyFilter("abc", hostile))
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(2))
+	})
+
+	It("can reject hostile OrderBy", func() {
+		_, err := stc.List(ctx, generated.WorldKindIdentity(),
+			options.OrderBy("external.name'); DROP TABLE Objects; --"))
+		Expect(err).ToNot(BeNil())
+
+		ret, err := stc.List(ctx, generated.WorldKindIdentity(),
+			options.OrderBy("external.name"))
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(3))
+		Expect(ret[0].PrimaryKey()).To(Equal("abc"))
+	})
+
+	It("can reject hostile Filter keys", func() {
+		_, err := sqlite.List(ctx, generated.WorldKindIdentity(),
+			options.Filter(filter.Eq("external.name') OR 1=1 --", "abc")))
+		Expect(err).ToNot(BeNil())
+	})
+})
@@ -0,0 +1,284 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/store/options"
+)
+
+// Counter is implemented by stores able to count the objects
+// matching the List filters without reading them
+type Counter interface {
+	Count(context.Context, ObjectIdentity, ...options.ListOption) (int, error)
+}
+
+// Aggregator is implemented by stores able to aggregate
+// the objects matching the List filters natively
+type Aggregator interface {
+	Aggregate(context.Context, ObjectIdentity, Aggregation, ...options.ListOption) ([]AggregateResult, error)
+}
+
+type AggregateFunction string
+
+const (
+	AggregateCount AggregateFunction = "count"
+	AggregateSum   AggregateFunction = "sum"
+	AggregateMin   AggregateFunction = "min"
+	AggregateMax   AggregateFunction = "max"
+)
+
+// Aggregation of a property of the objects, e.g. the sum of
+// external.nested.counter, per value of the GroupBy property when set
+type Aggregation struct {
+	Function AggregateFunction `json:"function"`
+	Property string            `json:"property,omitempty"`
+	GroupBy  string            `json:"groupBy,omitempty"`
+}
+
+// AggregateResult of a group, Group is nil without GroupBy.
+// Value is the sum, min or max of the property and nil when counting,
+// numbers are float64
+type AggregateResult struct {
+	Group interface{} `json:"group,omitempty"`
+	Count int         `json:"count"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Validate checks the function has the property it needs
+func (a Aggregation) Validate() error {
+	switch a.Function {
+	case AggregateCount:
+		return nil
+	case AggregateSum, AggregateMin, AggregateMax:
+		if len(a.Property) == 0 {
+			return fmt.Errorf("%w: %s needs a property", constants.ErrInvalidAggr, a.Function)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%w: unknown function %s", constants.ErrInvalidAggr, a.Function)
+}
+
+// Count uses the Counter of the store, or counts the listed objects
+func Count(
+	ctx context.Context,
+	st Store,
+	identity ObjectIdentity,
+	opt ...options.ListOption) (int, error) {
+
+	counter, ok := st.(Counter)
+	if ok {
+		return counter.Count(ctx, identity, opt...)
+	}
+
+	ret, err := st.List(ctx, identity, opt...)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(ret), nil
+}
+
+// Exists tells whether the object exists, or for kind identities
+// whether any object matches the List filters
+func Exists(
+	ctx context.Context,
+	st Store,
+	identity ObjectIdentity,
+	opt ...options.ListOption) (bool, error) {
+
+	if len(identity.Key()) == 0 {
+		count, err := Count(ctx, st, identity, opt...)
+		return count > 0, err
+	}
+
+	if len(opt) > 0 {
+		return false, constants.ErrInvalidPath
+	}
+
+	_, err := st.Get(ctx, identity)
+	if errors.Is(err, constants.ErrNoSuchObject) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// AggregateList uses the Aggregator of the store,
+// or aggregates the listed objects
+func AggregateList(
+	ctx context.Context,
+	st Store,
+	identity ObjectIdentity,
+	agg Aggregation,
+	opt ...options.ListOption) ([]AggregateResult, error) {
+
+	aggregator, ok := st.(Aggregator)
+	if ok {
+		return aggregator.Aggregate(ctx, identity, agg, opt...)
+	}
+
+	err := agg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := st.List(ctx, identity, opt...)
+	if err != nil {
+		return nil, err
+	}
+
+	return Aggregate(ret, agg)
+}
+
+// Aggregate the objects in memory, groups are ordered by their value.
+// Without GroupBy there is a single result, even for no objects
+func Aggregate(list ObjectList, agg Aggregation) ([]AggregateResult, error) {
+	err := agg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*AggregateResult{}
+	groups := make(map[string]*AggregateResult)
+	if len(agg.GroupBy) == 0 {
+		res = append(res, &AggregateResult{})
+		groups["null"] = res[0]
+	}
+
+	for _, obj := range list {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		var doc interface{}
+		err = json.Unmarshal(data, &doc)
+		if err != nil {
+			return nil, err
+		}
+
+		var group interface{}
+		if len(agg.GroupBy) > 0 {
+			group, err = propertyValue(doc, agg.GroupBy)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		key, _ := json.Marshal(group)
+		r := groups[string(key)]
+		if r == nil {
+			r = &AggregateResult{Group: group}
+			groups[string(key)] = r
+			res = append(res, r)
+		}
+
+		r.Count++
+		if agg.Function == AggregateCount {
+			continue
+		}
+
+		value, err := propertyValue(doc, agg.Property)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Value, err = accumulate(agg.Function, r.Value, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(res, func(p, q int) bool {
+		less, _ := lessValue(res[p].Group, res[q].Group)
+		return less
+	})
+
+	ret := make([]AggregateResult, len(res))
+	for i, r := range res {
+		ret[i] = *r
+	}
+
+	return ret, nil
+}
+
+func accumulate(fn AggregateFunction, current interface{}, value interface{}) (interface{}, error) {
+	if fn == AggregateSum {
+		v, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot sum %v", constants.ErrInvalidAggr, value)
+		}
+
+		sum, _ := current.(float64)
+		return sum + v, nil
+	}
+
+	if current == nil {
+		_, err := lessValue(value, value)
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+
+	a, b := value, current
+	if fn == AggregateMax {
+		a, b = current, value
+	}
+
+	less, err := lessValue(a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	if less {
+		return value, nil
+	}
+
+	return current, nil
+}
+
+// lessValue compares numbers, strings and booleans of the same type
+func lessValue(a interface{}, b interface{}) (bool, error) {
+	switch av := a.(type) {
+	case nil:
+		return b != nil, nil
+	case float64:
+		bv, ok := b.(float64)
+		if ok {
+			return av < bv, nil
+		}
+	case string:
+		bv, ok := b.(string)
+		if ok {
+			return av < bv, nil
+		}
+	case bool:
+		bv, ok := b.(bool)
+		if ok {
+			return !av && bv, nil
+		}
+	}
+
+	return false, fmt.Errorf("%w: cannot compare %v and %v", constants.ErrInvalidAggr, a, b)
+}
+
+func propertyValue(doc interface{}, path string) (interface{}, error) {
+	doc, ok := lookupPath(doc, path)
+	if !ok {
+		return nil, constants.ErrInvalidFilter
+	}
+
+	switch doc.(type) {
+	case map[string]interface{}, []interface{}:
+		return nil, fmt.Errorf("%w: %s is not a value", constants.ErrInvalidAggr, path)
+	}
+
+	return doc, nil
+}
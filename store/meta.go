@@ -5,6 +5,7 @@ type Meta interface {
 	Identity() ObjectIdentity
 	Created() string
 	Updated() string
+	Revision() int64
 }
 
 type MetaSetter interface {
@@ -12,6 +13,7 @@ type MetaSetter interface {
 	SetIdentity(ObjectIdentity)
 	SetCreated(string)
 	SetUpdated(string)
+	SetRevision(int64)
 }
 
 type MetaHolder interface {
@@ -23,6 +25,7 @@ type metaWrapper struct {
 	Identity_ *ObjectIdentity `json:"identity"`
 	Created_  *string         `json:"created"`
 	Updated_  *string         `json:"updated"`
+	Revision_ *int64          `json:"revision"`
 }
 
 func (m *metaWrapper) Kind() string {
@@ -37,6 +40,13 @@ func (m *metaWrapper) Updated() string {
 	return *m.Updated_
 }
 
+func (m *metaWrapper) Revision() int64 {
+	if m.Revision_ == nil {
+		return 0
+	}
+	return *m.Revision_
+}
+
 func (m *metaWrapper) Identity() ObjectIdentity {
 	return *m.Identity_
 }
@@ -57,15 +67,21 @@ func (m *metaWrapper) SetUpdated(updated string) {
 	m.Updated_ = &updated
 }
 
+func (m *metaWrapper) SetRevision(revision int64) {
+	m.Revision_ = &revision
+}
+
 func MetaFactory(kind string) Meta {
 	emptyIdentity := ObjectIdentityFactory()
 	emptyString1 := ""
 	emptyString2 := ""
+	emptyRevision := int64(0)
 	mw := metaWrapper{
 		Kind_:     &kind,
 		Identity_: &emptyIdentity,
 		Created_:  &emptyString1,
 		Updated_:  &emptyString2,
+		Revision_: &emptyRevision,
 	}
 
 	return &mw
@@ -3,6 +3,8 @@ package options
 import (
 	"errors"
 	"log"
+
+	"github.com/wazofski/gostorz/filter"
 )
 
 type Option interface {
@@ -34,6 +36,11 @@ type ListOption interface {
 	GetListOption() Option
 }
 
+type WatchOption interface {
+	Option
+	GetWatchOption() Option
+}
+
 type OptionHolder interface {
 	CommonOptions() *CommonOptionHolder
 }
@@ -50,10 +57,15 @@ type KeyFilterSetting []string
 type CommonOptionHolder struct {
 	PropFilter       *PropFilterSetting
 	KeyFilter        *KeyFilterSetting
+	Filter           *filter.Expression
+	Revision         *int64
 	OrderBy          string
 	OrderIncremental bool
 	PageSize         int
 	PageOffset       int
+	Continue         string
+	TotalCount       bool
+	Fields           []string
 }
 
 func (d *CommonOptionHolder) CommonOptions() *CommonOptionHolder {
@@ -64,15 +76,20 @@ func CommonOptionHolderFactory() CommonOptionHolder {
 	return CommonOptionHolder{
 		PropFilter:       nil,
 		KeyFilter:        nil,
+		Filter:           nil,
+		Revision:         nil,
 		OrderBy:          "",
 		OrderIncremental: true,
 		PageSize:         0,
 		PageOffset:       0,
+		Continue:         "",
+		TotalCount:       false,
+		Fields:           nil,
 	}
 }
 
-func PropFilter(prop string, val string) ListOption {
-	return listOption{
+func PropFilter(prop string, val string) FilterOption {
+	return listFilterOption{
 		Function: func(options OptionHolder) error {
 			commonOptions := options.CommonOptions()
 			if commonOptions.PropFilter != nil {
@@ -91,8 +108,8 @@ func PropFilter(prop string, val string) ListOption {
 	}
 }
 
-func KeyFilter(keys ...string) ListOption {
-	return listOption{
+func KeyFilter(keys ...string) FilterOption {
+	return listFilterOption{
 		Function: func(options OptionHolder) error {
 			if len(keys) == 0 {
 				log.Printf("ignoring empty key filter")
@@ -113,6 +130,39 @@ func KeyFilter(keys ...string) ListOption {
 	}
 }
 
+func Filter(expr *filter.Expression) FilterOption {
+	return listFilterOption{
+		Function: func(options OptionHolder) error {
+			commonOptions := options.CommonOptions()
+			if commonOptions.Filter != nil {
+				return errors.New("filter option already set")
+			}
+
+			err := expr.Validate()
+			if err != nil {
+				return err
+			}
+
+			commonOptions.Filter = expr
+			return nil
+		},
+	}
+}
+
+func IfRevision(revision int64) PreconditionOption {
+	return revisionOption{
+		Function: func(options OptionHolder) error {
+			commonOptions := options.CommonOptions()
+			if commonOptions.Revision != nil {
+				return errors.New("revision option already set")
+			}
+
+			commonOptions.Revision = &revision
+			return nil
+		},
+	}
+}
+
 func PageSize(ps int) ListOption {
 	return listOption{
 		Function: func(options OptionHolder) error {
@@ -144,6 +194,53 @@ func PageOffset(po int) ListOption {
 	}
 }
 
+// Continue lists the page after the one the token was returned with,
+// the token is opaque and cannot be combined with a page offset
+func Continue(token string) ListOption {
+	return listOption{
+		Function: func(options OptionHolder) error {
+			commonOptions := options.CommonOptions()
+			if len(commonOptions.Continue) > 0 {
+				return errors.New("continue option has already been set")
+			}
+			commonOptions.Continue = token
+			return nil
+		},
+	}
+}
+
+// TotalCount requests the number of objects matching the filters
+func TotalCount() ListOption {
+	return listOption{
+		Function: func(options OptionHolder) error {
+			commonOptions := options.CommonOptions()
+			if commonOptions.TotalCount {
+				return errors.New("total count option has already been set")
+			}
+			commonOptions.TotalCount = true
+			return nil
+		},
+	}
+}
+
+// Fields projects the objects to the property paths,
+// keeping the kind and identity of their metadata
+func Fields(fields ...string) ProjectionOption {
+	return readOption{
+		Function: func(options OptionHolder) error {
+			commonOptions := options.CommonOptions()
+			if commonOptions.Fields != nil {
+				return errors.New("fields option has already been set")
+			}
+			if len(fields) == 0 {
+				return errors.New("fields option needs at least one field")
+			}
+			commonOptions.Fields = fields
+			return nil
+		},
+	}
+}
+
 func OrderBy(field string) ListOption {
 	return listOption{
 		Function: func(options OptionHolder) error {
@@ -183,3 +280,69 @@ func (d listOption) GetListOption() Option {
 func (d listOption) ApplyFunction() OptionFunction {
 	return d.Function
 }
+
+// FilterOption selects the objects of both List and Watch
+type FilterOption interface {
+	ListOption
+	WatchOption
+}
+
+type listFilterOption struct {
+	Function OptionFunction
+}
+
+func (d listFilterOption) GetListOption() Option {
+	return d
+}
+
+func (d listFilterOption) GetWatchOption() Option {
+	return d
+}
+
+func (d listFilterOption) ApplyFunction() OptionFunction {
+	return d.Function
+}
+
+// ProjectionOption trims the objects of both Get and List
+type ProjectionOption interface {
+	GetOption
+	ListOption
+}
+
+type readOption struct {
+	Function OptionFunction
+}
+
+func (d readOption) GetGetOption() Option {
+	return d
+}
+
+func (d readOption) GetListOption() Option {
+	return d
+}
+
+func (d readOption) ApplyFunction() OptionFunction {
+	return d.Function
+}
+
+// PreconditionOption guards both Update and Delete
+type PreconditionOption interface {
+	UpdateOption
+	DeleteOption
+}
+
+type revisionOption struct {
+	Function OptionFunction
+}
+
+func (d revisionOption) GetUpdateOption() Option {
+	return d
+}
+
+func (d revisionOption) GetDeleteOption() Option {
+	return d
+}
+
+func (d revisionOption) ApplyFunction() OptionFunction {
+	return d.Function
+}
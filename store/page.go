@@ -0,0 +1,22 @@
+package store
+
+import (
+	"context"
+
+	"github.com/wazofski/gostorz/store/options"
+)
+
+// Page of a List, Continue is empty on the last page
+// and Total is only set when requested with options.TotalCount
+type Page struct {
+	Items    ObjectList
+	Continue string
+	Total    *int
+}
+
+// Pager is implemented by stores able to continue a List where its
+// previous page ended, unaffected by objects created in between.
+// Pass the Continue token of a page back with options.Continue
+type Pager interface {
+	ListPage(context.Context, ObjectIdentity, ...options.ListOption) (*Page, error)
+}
@@ -0,0 +1,169 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/wazofski/gostorz/internal/constants"
+	"github.com/wazofski/gostorz/internal/jsonpatch"
+	"github.com/wazofski/gostorz/store/options"
+)
+
+type PatchType string
+
+const (
+	MergePatchType PatchType = "application/merge-patch+json"
+	JSONPatchType  PatchType = "application/json-patch+json"
+)
+
+// Patch is an RFC 7386 merge patch or an RFC 6902 JSON patch
+// of the object JSON, e.g. {"external": {"name": "abc"}}
+type Patch struct {
+	Type PatchType
+	Data []byte
+}
+
+// MergePatch makes an RFC 7386 merge patch
+func MergePatch(data []byte) Patch {
+	return Patch{Type: MergePatchType, Data: data}
+}
+
+// JSONPatch makes an RFC 6902 JSON patch
+func JSONPatch(data []byte) Patch {
+	return Patch{Type: JSONPatchType, Data: data}
+}
+
+// Patcher is implemented by stores able to apply patches atomically.
+// Update options like IfRevision apply to the patched object.
+type Patcher interface {
+	Patch(context.Context, ObjectIdentity, Patch, ...options.UpdateOption) (Object, error)
+}
+
+// patchRetries bounds the attempts of PatchUpdate racing other writes
+const patchRetries = 10
+
+// ApplyPatch returns the validated patched copy of the object.
+// Patches cannot change the kind, identity, creation time or revision
+func ApplyPatch(schema SchemaHolder, obj Object, patch Patch) (Object, error) {
+	if obj == nil {
+		return nil, constants.ErrObjectNil
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	switch patch.Type {
+	case MergePatchType:
+		data, err = jsonpatch.Merge(data, patch.Data)
+	case JSONPatchType:
+		data, err = jsonpatch.Apply(data, patch.Data)
+	default:
+		return nil, fmt.Errorf("%w: unsupported type %s",
+			constants.ErrInvalidPatch, patch.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = keepMetadata(obj, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// removed properties fall back to the defaults of a new object
+	res := schema.ObjectForKind(obj.Metadata().Kind())
+	if res == nil {
+		return nil, constants.ErrUnknownKind
+	}
+
+	err = res.UnmarshalJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", constants.ErrInvalidPatch, err)
+	}
+
+	err = Validate(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// keepMetadata restores the metadata of the object in the
+// patched JSON, all but the update time
+func keepMetadata(obj Object, data []byte) ([]byte, error) {
+	patched := map[string]interface{}{}
+	err := json.Unmarshal(data, &patched)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", constants.ErrInvalidPatch, err)
+	}
+
+	meta := map[string]interface{}{}
+	original, err := json.Marshal(obj.Metadata())
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(original, &meta)
+	if err != nil {
+		return nil, err
+	}
+
+	patchedMeta, _ := patched["metadata"].(map[string]interface{})
+	updated, ok := patchedMeta["updated"].(string)
+	if ok {
+		meta["updated"] = updated
+	}
+
+	patched["metadata"] = meta
+	return json.Marshal(patched)
+}
+
+// PatchUpdate patches the object through the Get and Update of the store,
+// the update requires the revision the patch was applied to and is retried
+// on conflicts unless the options require a revision themselves
+func PatchUpdate(
+	ctx context.Context,
+	schema SchemaHolder,
+	st Store,
+	identity ObjectIdentity,
+	patch Patch,
+	opt ...options.UpdateOption) (Object, error) {
+
+	copt := options.CommonOptionHolderFactory()
+	for _, o := range opt {
+		err := o.ApplyFunction()(&copt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		existing, err := st.Get(ctx, identity)
+		if err != nil {
+			return nil, err
+		}
+
+		revision := existing.Metadata().Revision()
+		if copt.Revision != nil && *copt.Revision != revision {
+			return nil, constants.ErrConflict
+		}
+
+		patched, err := ApplyPatch(schema, existing, patch)
+		if err != nil {
+			return nil, err
+		}
+
+		// committed updates return the object along their errors
+		ret, err := st.Update(ctx, identity, patched, options.IfRevision(revision))
+		if ret == nil && errors.Is(err, constants.ErrConflict) &&
+			copt.Revision == nil && attempt < patchRetries {
+			continue
+		}
+
+		return ret, err
+	}
+}
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/wazofski/gostorz/internal/constants"
+)
+
+// projectionKept are the metadata paths projections always keep
+var projectionKept = []string{"metadata.kind", "metadata.identity"}
+
+// ProjectionPaths returns the sorted paths to read for the fields,
+// the kept metadata included and the paths under another one left out
+func ProjectionPaths(fields []string) []string {
+	paths := append(append([]string{}, projectionKept...), fields...)
+	sort.Strings(paths)
+
+	res := []string{}
+	for _, p := range paths {
+		covered := false
+		for _, r := range paths {
+			if p == r {
+				continue
+			}
+			if strings.HasPrefix(p, r+".") {
+				covered = true
+				break
+			}
+		}
+
+		if !covered && (len(res) == 0 || res[len(res)-1] != p) {
+			res = append(res, p)
+		}
+	}
+
+	return res
+}
+
+// Projection returns the JSON document of the object trimmed to the fields,
+// keeping the kind and identity of its metadata.
+// Fields missing from the object fail with ErrInvalidFilter
+func Projection(obj Object, fields []string) (map[string]interface{}, error) {
+	if obj == nil {
+		return nil, constants.ErrObjectNil
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := make(map[string]interface{})
+	err = json.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]interface{})
+	for _, p := range ProjectionPaths(fields) {
+		value, ok := lookupPath(doc, p)
+		if !ok {
+			if isKept(p) {
+				continue
+			}
+			return nil, constants.ErrInvalidFilter
+		}
+
+		setPath(res, p, value)
+	}
+
+	return res, nil
+}
+
+// Project trims the object to the fields, the other properties
+// keep the defaults of a new object of the kind
+func Project(schema SchemaHolder, obj Object, fields []string) (Object, error) {
+	if len(fields) == 0 {
+		return obj, nil
+	}
+
+	doc, err := Projection(obj, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := schema.ObjectForKind(obj.Metadata().Kind())
+	if ret == nil {
+		return nil, constants.ErrUnknownKind
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// ProjectList trims the listed objects of the kind to the fields,
+// checking them against the schema even when nothing is listed
+func ProjectList(schema SchemaHolder, kind string, list ObjectList, fields []string) (ObjectList, error) {
+	if len(fields) == 0 {
+		return list, nil
+	}
+
+	obj := schema.ObjectForKind(kind)
+	if obj == nil {
+		return nil, constants.ErrUnknownKind
+	}
+
+	_, err := Projection(obj, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	res := ObjectList{}
+	for _, o := range list {
+		p, err := Project(schema, o, fields)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, p)
+	}
+
+	return res, nil
+}
+
+func isKept(path string) bool {
+	for _, k := range projectionKept {
+		if k == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+func lookupPath(doc interface{}, path string) (interface{}, bool) {
+	for _, t := range strings.Split(path, ".") {
+		node, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		doc, ok = node[t]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return doc, true
+}
+
+func setPath(doc map[string]interface{}, path string, value interface{}) {
+	tokens := strings.Split(path, ".")
+	for _, t := range tokens[:len(tokens)-1] {
+		node, ok := doc[t].(map[string]interface{})
+		if !ok {
+			node = make(map[string]interface{})
+			doc[t] = node
+		}
+		doc = node
+	}
+
+	doc[tokens[len(tokens)-1]] = value
+}
@@ -73,6 +73,12 @@ type SchemaHolder interface {
 	Types() []string
 }
 
+// Describer is implemented by schemas able to describe
+// their types as OpenAPI component schemas in JSON
+type Describer interface {
+	OpenAPI() string
+}
+
 type Factory func(schema SchemaHolder) (Store, error)
 
 func New(schema SchemaHolder, factory Factory) Store {
@@ -82,3 +88,10 @@ func New(schema SchemaHolder, factory Factory) Store {
 	}
 	return store
 }
+
+// Transactional is implemented by stores able to apply several
+// mutations atomically. Mutations must go through the provided store;
+// returning an error from the callback rolls all of them back.
+type Transactional interface {
+	Txn(context.Context, func(Store) error) error
+}
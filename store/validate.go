@@ -0,0 +1,31 @@
+package store
+
+import (
+	"fmt"
+)
+
+// Validator is implemented by objects carrying model constraints.
+// Generated objects validate their External and Internal properties.
+type Validator interface {
+	Validate() error
+}
+
+// ValidationError reports the property path violating a model constraint
+type ValidationError struct {
+	Path   string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Path, e.Reason)
+}
+
+// Validate checks the constraints of objects implementing Validator
+func Validate(obj Object) error {
+	validator, ok := obj.(Validator)
+	if !ok {
+		return nil
+	}
+
+	return validator.Validate()
+}
@@ -0,0 +1,27 @@
+package store
+
+import (
+	"context"
+
+	"github.com/wazofski/gostorz/store/options"
+)
+
+type EventType string
+
+const (
+	EventCreated EventType = "created"
+	EventUpdated EventType = "updated"
+	EventDeleted EventType = "deleted"
+)
+
+type Event struct {
+	Type   EventType `json:"type"`
+	Object Object    `json:"object"`
+}
+
+// Watcher is implemented by stores able to stream object changes.
+// The returned channel is closed when the context is cancelled or
+// when the watcher falls too far behind and has to re-List.
+type Watcher interface {
+	Watch(context.Context, ObjectIdentity, ...options.WatchOption) (<-chan Event, error)
+}
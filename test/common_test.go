@@ -1,12 +1,17 @@
 package common_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"sort"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/wazofski/gostorz/filter"
 	"github.com/wazofski/gostorz/generated"
+	"github.com/wazofski/gostorz/internal/constants"
 	"github.com/wazofski/gostorz/store"
 	"github.com/wazofski/gostorz/store/options"
 	"github.com/wazofski/gostorz/utils"
@@ -465,6 +470,343 @@ var _ = Describe("common", func() {
 		Expect(world.External().Name()).To(Equal(anotherWorldName))
 	})
 
+	It("can LIST and continue pages", func() {
+		pager, ok := clt.(store.Pager)
+		if !ok {
+			Skip("store does not support paging")
+		}
+
+		page, err := pager.ListPage(
+			ctx,
+			generated.WorldKindIdentity(),
+			options.OrderBy("external.name"),
+			options.PageSize(1),
+			options.TotalCount())
+
+		Expect(err).To(BeNil())
+		Expect(len(page.Items)).To(Equal(1))
+		Expect(page.Items[0].PrimaryKey()).To(Equal(worldName))
+		Expect(page.Continue).ToNot(BeEmpty())
+		Expect(page.Total).ToNot(BeNil())
+		Expect(*page.Total).To(Equal(2))
+
+		// objects inserted before the cursor do not shift the next page
+		world := generated.WorldFactory()
+		world.External().SetName("a0 paged")
+		_, err = clt.Create(ctx, world)
+		Expect(err).To(BeNil())
+
+		next, err := pager.ListPage(
+			ctx,
+			generated.WorldKindIdentity(),
+			options.OrderBy("external.name"),
+			options.PageSize(1),
+			options.Continue(page.Continue),
+			options.TotalCount())
+
+		Expect(err).To(BeNil())
+		Expect(len(next.Items)).To(Equal(1))
+		Expect(next.Items[0].PrimaryKey()).To(Equal(anotherWorldName))
+		Expect(next.Continue).To(BeEmpty())
+		Expect(*next.Total).To(Equal(3))
+
+		// listings without order run by primary key
+		page, err = pager.ListPage(
+			ctx,
+			generated.WorldKindIdentity(),
+			options.PageSize(2))
+
+		Expect(err).To(BeNil())
+		Expect(len(page.Items)).To(Equal(2))
+		Expect(page.Items[0].PrimaryKey()).To(Equal("a0 paged"))
+		Expect(page.Items[1].PrimaryKey()).To(Equal(worldName))
+		Expect(page.Total).To(BeNil())
+
+		ret, err := clt.List(
+			ctx,
+			generated.WorldKindIdentity(),
+			options.PageSize(2),
+			options.Continue(page.Continue))
+
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(1))
+		Expect(ret[0].PrimaryKey()).To(Equal(anotherWorldName))
+
+		page, err = pager.ListPage(
+			ctx,
+			generated.WorldKindIdentity(),
+			options.OrderBy("external.name"),
+			options.OrderDescending(),
+			options.PageSize(2))
+
+		Expect(err).To(BeNil())
+		Expect(page.Items[0].PrimaryKey()).To(Equal(anotherWorldName))
+		Expect(page.Items[1].PrimaryKey()).To(Equal(worldName))
+
+		next, err = pager.ListPage(
+			ctx,
+			generated.WorldKindIdentity(),
+			options.OrderBy("external.name"),
+			options.OrderDescending(),
+			options.PageSize(2),
+			options.Continue(page.Continue))
+
+		Expect(err).To(BeNil())
+		Expect(len(next.Items)).To(Equal(1))
+		Expect(next.Items[0].PrimaryKey()).To(Equal("a0 paged"))
+		Expect(next.Continue).To(BeEmpty())
+
+		// tokens only continue the listing they came from
+		_, err = clt.List(
+			ctx,
+			generated.WorldKindIdentity(),
+			options.OrderBy("external.name"),
+			options.Continue(page.Continue))
+		Expect(errors.Is(err, constants.ErrInvalidToken)).To(BeTrue())
+
+		_, err = clt.List(
+			ctx,
+			generated.WorldKindIdentity(),
+			options.OrderBy("external.name"),
+			options.OrderDescending(),
+			options.PageOffset(1),
+			options.Continue(page.Continue))
+		Expect(errors.Is(err, constants.ErrInvalidToken)).To(BeTrue())
+
+		_, err = clt.List(
+			ctx,
+			generated.WorldKindIdentity(),
+			options.Continue("garbage"))
+		Expect(errors.Is(err, constants.ErrInvalidToken)).To(BeTrue())
+
+		err = clt.Delete(ctx, generated.WorldIdentity("a0 paged"))
+		Expect(err).To(BeNil())
+	})
+
+	It("can COUNT and AGGREGATE objects", func() {
+		_, ok := clt.(store.Counter)
+		Expect(ok).To(BeTrue())
+
+		for i, name := range []string{"agg a", "agg b", "agg c"} {
+			world := generated.WorldFactory()
+			world.External().SetName(name)
+			world.External().SetDescription("aggregated")
+			world.External().Nested().SetCounter(1 << i)
+			if i == 2 {
+				world.External().SetDescription("other")
+				world.External().Nested().SetAlive(true)
+			}
+
+			_, err := clt.Create(ctx, world)
+			Expect(err).To(BeNil())
+		}
+
+		aggregated := options.KeyFilter("agg a", "agg b", "agg c")
+
+		count, err := store.Count(ctx, clt, generated.WorldKindIdentity(), aggregated)
+		Expect(err).To(BeNil())
+		Expect(count).To(Equal(3))
+
+		count, err = store.Count(ctx, clt, generated.WorldKindIdentity(),
+			options.KeyFilter("agg a", "agg z"))
+		Expect(err).To(BeNil())
+		Expect(count).To(Equal(1))
+
+		exists, err := store.Exists(ctx, clt, generated.WorldIdentity("agg a"))
+		Expect(err).To(BeNil())
+		Expect(exists).To(BeTrue())
+
+		exists, err = store.Exists(ctx, clt, generated.WorldIdentity("agg z"))
+		Expect(err).To(BeNil())
+		Expect(exists).To(BeFalse())
+
+		exists, err = store.Exists(ctx, clt, generated.WorldKindIdentity(),
+			options.KeyFilter("agg z"))
+		Expect(err).To(BeNil())
+		Expect(exists).To(BeFalse())
+
+		aggregate := func(agg store.Aggregation, opt ...options.ListOption) []store.AggregateResult {
+			ret, err := store.AggregateList(ctx, clt, generated.WorldKindIdentity(), agg, opt...)
+			Expect(err).To(BeNil())
+			return ret
+		}
+
+		Expect(aggregate(store.Aggregation{
+			Function: store.AggregateSum,
+			Property: "external.nested.counter",
+		}, aggregated)).To(Equal([]store.AggregateResult{
+			{Count: 3, Value: 7.0},
+		}))
+
+		Expect(aggregate(store.Aggregation{
+			Function: store.AggregateMin,
+			Property: "external.nested.counter",
+		}, aggregated)).To(Equal([]store.AggregateResult{
+			{Count: 3, Value: 1.0},
+		}))
+
+		Expect(aggregate(store.Aggregation{
+			Function: store.AggregateMax,
+			Property: "external.nested.counter",
+		}, aggregated)).To(Equal([]store.AggregateResult{
+			{Count: 3, Value: 4.0},
+		}))
+
+		Expect(aggregate(store.Aggregation{
+			Function: store.AggregateSum,
+			Property: "external.nested.counter",
+			GroupBy:  "external.description",
+		}, aggregated)).To(Equal([]store.AggregateResult{
+			{Group: "aggregated", Count: 2, Value: 3.0},
+			{Group: "other", Count: 1, Value: 4.0},
+		}))
+
+		Expect(aggregate(store.Aggregation{
+			Function: store.AggregateMax,
+			Property: "external.name",
+			GroupBy:  "external.description",
+		}, aggregated)).To(Equal([]store.AggregateResult{
+			{Group: "aggregated", Count: 2, Value: "agg b"},
+			{Group: "other", Count: 1, Value: "agg c"},
+		}))
+
+		Expect(aggregate(store.Aggregation{
+			Function: store.AggregateCount,
+			GroupBy:  "external.nested.alive",
+		}, aggregated, options.PropFilter("external.description", "aggregated"))).
+			To(Equal([]store.AggregateResult{
+				{Group: false, Count: 2},
+			}))
+
+		Expect(aggregate(store.Aggregation{
+			Function: store.AggregateCount,
+			GroupBy:  "external.nested.alive",
+		}, aggregated)).To(Equal([]store.AggregateResult{
+			{Group: false, Count: 2},
+			{Group: true, Count: 1},
+		}))
+
+		Expect(aggregate(store.Aggregation{
+			Function: store.AggregateSum,
+			Property: "external.nested.counter",
+		}, options.KeyFilter("agg z"))).To(Equal([]store.AggregateResult{
+			{Count: 0},
+		}))
+
+		_, err = store.AggregateList(ctx, clt, generated.WorldKindIdentity(),
+			store.Aggregation{Function: store.AggregateSum, Property: "external.name"},
+			aggregated)
+		Expect(errors.Is(err, constants.ErrInvalidAggr)).To(BeTrue())
+
+		_, err = store.AggregateList(ctx, clt, generated.WorldKindIdentity(),
+			store.Aggregation{Function: "median", Property: "external.nested.counter"},
+			aggregated)
+		Expect(errors.Is(err, constants.ErrInvalidAggr)).To(BeTrue())
+
+		_, err = store.AggregateList(ctx, clt, generated.WorldKindIdentity(),
+			store.Aggregation{Function: store.AggregateMax, Property: "external.nothing"},
+			aggregated)
+		Expect(errors.Is(err, constants.ErrInvalidFilter)).To(BeTrue())
+
+		for _, name := range []string{"agg a", "agg b", "agg c"} {
+			Expect(clt.Delete(ctx, generated.WorldIdentity(name))).To(Succeed())
+		}
+	})
+
+	It("can GET and LIST projected fields", func() {
+		created := map[string]store.Object{}
+		for i, name := range []string{"proj a", "proj b"} {
+			world := generated.WorldFactory()
+			world.External().SetName(name)
+			world.External().SetDescription("projected")
+			world.External().Nested().SetCounter(3 + i)
+			world.External().Nested().SetAlive(true)
+
+			ret, err := clt.Create(ctx, world)
+			Expect(err).To(BeNil())
+			created[name] = ret
+		}
+
+		ret, err := clt.Get(ctx, generated.WorldIdentity("proj a"),
+			options.Fields("external.nested.counter", "metadata.created"))
+		Expect(err).To(BeNil())
+
+		world := ret.(generated.World)
+		Expect(world.Metadata().Kind()).To(Equal("World"))
+		Expect(world.Metadata().Identity()).To(Equal(created["proj a"].Metadata().Identity()))
+		Expect(world.Metadata().Created()).To(Equal(created["proj a"].Metadata().Created()))
+		Expect(world.Metadata().Revision()).To(BeZero())
+		Expect(world.External().Nested().Counter()).To(Equal(3))
+		Expect(world.External().Nested().Alive()).To(BeFalse())
+		Expect(world.External().Name()).To(BeEmpty())
+		Expect(world.External().Description()).To(BeEmpty())
+
+		ret, err = clt.Get(ctx, created["proj b"].Metadata().Identity(),
+			options.Fields("external"))
+		Expect(err).To(BeNil())
+
+		world = ret.(generated.World)
+		Expect(world.External().Name()).To(Equal("proj b"))
+		Expect(world.External().Nested().Alive()).To(BeTrue())
+
+		// projections leave the whole object in place
+		ret, err = clt.Get(ctx, generated.WorldIdentity("proj a"))
+		Expect(err).To(BeNil())
+		Expect(ret.(generated.World).External().Description()).To(Equal("projected"))
+		Expect(ret.Metadata().Revision()).ToNot(BeZero())
+
+		projected := options.KeyFilter("proj a", "proj b")
+		ret, err = clt.Get(ctx, generated.WorldIdentity("proj a"),
+			options.Fields("external.nothing"))
+		Expect(errors.Is(err, constants.ErrInvalidFilter)).To(BeTrue())
+		Expect(ret).To(BeNil())
+
+		_, err = clt.List(ctx, generated.WorldKindIdentity(),
+			projected, options.Fields("external.nothing"))
+		Expect(errors.Is(err, constants.ErrInvalidFilter)).To(BeTrue())
+
+		list, err := clt.List(ctx, generated.WorldKindIdentity(),
+			projected,
+			options.Fields("external.name"),
+			options.OrderBy("external.nested.counter"),
+			options.OrderDescending())
+		Expect(err).To(BeNil())
+		Expect(len(list)).To(Equal(2))
+		Expect(list[0].PrimaryKey()).To(Equal("proj b"))
+		Expect(list[0].(generated.World).External().Nested().Counter()).To(BeZero())
+		Expect(list[0].(generated.World).External().Description()).To(BeEmpty())
+		Expect(list[1].PrimaryKey()).To(Equal("proj a"))
+
+		pager, ok := clt.(store.Pager)
+		if ok {
+			page, err := pager.ListPage(ctx, generated.WorldKindIdentity(),
+				projected,
+				options.Fields("external.description"),
+				options.OrderBy("external.nested.counter"),
+				options.PageSize(1))
+			Expect(err).To(BeNil())
+			Expect(len(page.Items)).To(Equal(1))
+			Expect(page.Items[0].Metadata().Identity()).To(Equal(created["proj a"].Metadata().Identity()))
+			Expect(page.Items[0].(generated.World).External().Description()).To(Equal("projected"))
+			Expect(page.Items[0].(generated.World).External().Nested().Counter()).To(BeZero())
+
+			page, err = pager.ListPage(ctx, generated.WorldKindIdentity(),
+				projected,
+				options.Fields("external.description"),
+				options.OrderBy("external.nested.counter"),
+				options.PageSize(1),
+				options.Continue(page.Continue))
+			Expect(err).To(BeNil())
+			Expect(len(page.Items)).To(Equal(1))
+			Expect(page.Items[0].Metadata().Identity()).To(Equal(created["proj b"].Metadata().Identity()))
+			Expect(page.Continue).To(BeEmpty())
+		}
+
+		for _, name := range []string{"proj a", "proj b"} {
+			Expect(clt.Delete(ctx, generated.WorldIdentity(name))).To(Succeed())
+		}
+	})
+
 	It("can LIST and filter by primary key", func() {
 		ret, err := clt.List(
 			ctx, generated.WorldKindIdentity())
@@ -554,4 +896,344 @@ var _ = Describe("common", func() {
 		Expect(world.External().Description()).To(Equal(worldDescription))
 	})
 
+	It("can LIST and FILTER BY expression", func() {
+		ret, err := clt.List(
+			ctx, generated.WorldKindIdentity(),
+			options.Filter(filter.Gt("external.name", "d")))
+
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(1))
+		Expect(ret[0].PrimaryKey()).To(Equal(anotherWorldName))
+
+		ret, err = clt.List(
+			ctx, generated.WorldKindIdentity(),
+			options.Filter(filter.And(
+				filter.Ge("external.name", "a"),
+				filter.Lt("external.name", "d"))))
+
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(1))
+		Expect(ret[0].PrimaryKey()).To(Equal(worldName))
+
+		ret, err = clt.List(
+			ctx, generated.WorldKindIdentity(),
+			options.Filter(filter.Or(
+				filter.Eq("external.name", worldName),
+				filter.Eq("external.description", newWorldDescription))))
+
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(2))
+
+		ret, err = clt.List(
+			ctx, generated.WorldKindIdentity(),
+			options.Filter(filter.Not(
+				filter.In("external.name", worldName, "zzz"))))
+
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(1))
+		Expect(ret[0].PrimaryKey()).To(Equal(anotherWorldName))
+
+		ret, err = clt.List(
+			ctx, generated.WorldKindIdentity(),
+			options.Filter(filter.Le("external.nested.counter", 0)),
+			options.OrderBy("external.name"))
+
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(2))
+		Expect(ret[0].PrimaryKey()).To(Equal(worldName))
+	})
+
+	It("cannot LIST and FILTER BY expression on nonexistent props", func() {
+		ret, err := clt.List(
+			ctx, generated.WorldKindIdentity(),
+			options.Filter(filter.Eq("external.askdjhasd", "asdsadas")))
+
+		Expect(err).ToNot(BeNil())
+		Expect(ret).To(BeNil())
+	})
+
+	It("can LIST and FILTER numbers numerically", func() {
+		names := []string{"num a", "num b", "num c"}
+		for i, counter := range []int{10, 2, 9} {
+			world := generated.WorldFactory()
+			world.External().SetName(names[i])
+			world.External().Nested().SetCounter(counter)
+			_, err := clt.Create(ctx, world)
+			Expect(err).To(BeNil())
+		}
+
+		ret, err := clt.List(
+			ctx, generated.WorldKindIdentity(),
+			options.KeyFilter(names...),
+			options.OrderBy("external.nested.counter"))
+
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(3))
+		Expect(ret[0].PrimaryKey()).To(Equal("num b"))
+		Expect(ret[1].PrimaryKey()).To(Equal("num c"))
+		Expect(ret[2].PrimaryKey()).To(Equal("num a"))
+
+		ret, err = clt.List(
+			ctx, generated.WorldKindIdentity(),
+			options.KeyFilter(names...),
+			options.Filter(filter.Gt("external.nested.counter", 5)),
+			options.OrderBy("external.nested.counter"),
+			options.OrderDescending())
+
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(2))
+		Expect(ret[0].PrimaryKey()).To(Equal("num a"))
+		Expect(ret[1].PrimaryKey()).To(Equal("num c"))
+
+		for _, name := range names {
+			Expect(clt.Delete(ctx, generated.WorldIdentity(name))).To(Succeed())
+		}
+	})
+
+	It("can track object revisions", func() {
+		w := generated.WorldFactory()
+		w.External().SetName("revisioned")
+
+		ret, err := clt.Create(ctx, w)
+		Expect(err).To(BeNil())
+		Expect(ret.Metadata().Revision()).To(Equal(int64(1)))
+
+		w = ret.(generated.World)
+		w.External().SetDescription("first")
+		ret, err = clt.Update(ctx, w.Metadata().Identity(), w)
+		Expect(err).To(BeNil())
+		Expect(ret.Metadata().Revision()).To(Equal(int64(2)))
+
+		ret, err = clt.Get(ctx, generated.WorldIdentity("revisioned"))
+		Expect(err).To(BeNil())
+		Expect(ret.Metadata().Revision()).To(Equal(int64(2)))
+	})
+
+	It("cannot UPDATE or DELETE stale revisions", func() {
+		ret, err := clt.Get(ctx, generated.WorldIdentity("revisioned"))
+		Expect(err).To(BeNil())
+
+		w := ret.(generated.World)
+		w.External().SetDescription("second")
+
+		ret, err = clt.Update(ctx, w.Metadata().Identity(), w,
+			options.IfRevision(1))
+		Expect(err).ToNot(BeNil())
+		Expect(ret).To(BeNil())
+
+		ret, err = clt.Update(ctx, w.Metadata().Identity(), w,
+			options.IfRevision(2))
+		Expect(err).To(BeNil())
+		Expect(ret.Metadata().Revision()).To(Equal(int64(3)))
+
+		err = clt.Delete(ctx, w.Metadata().Identity(),
+			options.IfRevision(2))
+		Expect(err).ToNot(BeNil())
+
+		err = clt.Delete(ctx, w.Metadata().Identity(),
+			options.IfRevision(3))
+		Expect(err).To(BeNil())
+	})
+
+	It("can commit transactions", func() {
+		transactional, ok := clt.(store.Transactional)
+		if !ok {
+			Skip("store does not support transactions")
+		}
+
+		err := transactional.Txn(ctx, func(tx store.Store) error {
+			w := generated.WorldFactory()
+			w.External().SetName("transacted")
+
+			_, err := tx.Create(ctx, w)
+			if err != nil {
+				return err
+			}
+
+			ret, err := tx.Get(ctx, generated.WorldIdentity(worldName))
+			if err != nil {
+				return err
+			}
+
+			w = ret.(generated.World)
+			w.External().SetDescription("transacted")
+			_, err = tx.Update(ctx, w.Metadata().Identity(), w)
+			return err
+		})
+		Expect(err).To(BeNil())
+
+		ret, err := clt.Get(ctx, generated.WorldIdentity("transacted"))
+		Expect(err).To(BeNil())
+		Expect(ret).ToNot(BeNil())
+
+		ret, err = clt.Get(ctx, generated.WorldIdentity(worldName))
+		Expect(err).To(BeNil())
+		Expect(ret.(generated.World).External().Description()).To(Equal("transacted"))
+	})
+
+	It("can rollback transactions", func() {
+		transactional, ok := clt.(store.Transactional)
+		if !ok {
+			Skip("store does not support transactions")
+		}
+
+		err := transactional.Txn(ctx, func(tx store.Store) error {
+			w := generated.WorldFactory()
+			w.External().SetName("rolledback")
+
+			_, err := tx.Create(ctx, w)
+			if err != nil {
+				return err
+			}
+
+			err = tx.Delete(ctx, generated.WorldIdentity("transacted"))
+			if err != nil {
+				return err
+			}
+
+			ret, err := tx.Get(ctx, generated.WorldIdentity(worldName))
+			if err != nil {
+				return err
+			}
+
+			w = ret.(generated.World)
+			w.External().SetDescription(worldDescription)
+			_, err = tx.Update(ctx, w.Metadata().Identity(), w)
+			if err != nil {
+				return err
+			}
+
+			return fmt.Errorf("rollback")
+		})
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(Equal("rollback"))
+
+		_, err = clt.Get(ctx, generated.WorldIdentity("rolledback"))
+		Expect(err).ToNot(BeNil())
+
+		ret, err := clt.Get(ctx, generated.WorldIdentity("transacted"))
+		Expect(err).To(BeNil())
+
+		ret, err = clt.Get(ctx, generated.WorldIdentity(worldName))
+		Expect(err).To(BeNil())
+		Expect(ret.(generated.World).External().Description()).To(Equal("transacted"))
+
+		err = clt.Delete(ctx, ret.Metadata().Identity())
+		Expect(err).To(BeNil())
+		err = clt.Delete(ctx, generated.WorldIdentity("transacted"))
+		Expect(err).To(BeNil())
+	})
+
+	It("can PATCH objects", func() {
+		patcher, ok := clt.(store.Patcher)
+		if !ok {
+			Skip("store does not support patching")
+		}
+
+		w := generated.WorldFactory()
+		w.External().SetName("patched")
+		w.External().SetDescription("original")
+		w.External().Nested().SetCounter(1)
+		created, err := clt.Create(ctx, w)
+		Expect(err).To(BeNil())
+
+		ret, err := patcher.Patch(ctx, generated.WorldIdentity("patched"),
+			store.MergePatch([]byte(`{"external": {"nested": {"counter": 5, "alive": true}}}`)))
+		Expect(err).To(BeNil())
+		patched := ret.(generated.World)
+		Expect(patched.External().Description()).To(Equal("original"))
+		Expect(patched.External().Nested().Counter()).To(Equal(5))
+		Expect(patched.External().Nested().Alive()).To(BeTrue())
+		Expect(patched.Metadata().Identity()).To(Equal(created.Metadata().Identity()))
+		Expect(patched.Metadata().Revision()).To(Equal(int64(2)))
+
+		ret, err = patcher.Patch(ctx, created.Metadata().Identity(),
+			store.JSONPatch([]byte(`[
+				{"op": "test", "path": "/external/nested/counter", "value": 5},
+				{"op": "replace", "path": "/external/description", "value": "json"},
+				{"op": "add", "path": "/external/nested/l1/-", "value": true},
+				{"op": "remove", "path": "/external/nested/alive"}
+			]`)))
+		Expect(err).To(BeNil())
+		patched = ret.(generated.World)
+		Expect(patched.External().Description()).To(Equal("json"))
+		Expect(patched.External().Nested().L1()).To(Equal([]bool{true}))
+		Expect(patched.External().Nested().Alive()).To(BeFalse())
+
+		ret, err = clt.Get(ctx, generated.WorldIdentity("patched"))
+		Expect(err).To(BeNil())
+		Expect(ret.(generated.World).External().Description()).To(Equal("json"))
+		Expect(ret.Metadata().Revision()).To(Equal(int64(3)))
+
+		_, err = patcher.Patch(ctx, generated.WorldIdentity("patched"),
+			store.JSONPatch([]byte(`[{"op": "test", "path": "/external/description", "value": "other"}]`)))
+		Expect(errors.Is(err, constants.ErrConflict)).To(BeTrue())
+
+		_, err = patcher.Patch(ctx, generated.WorldIdentity("patched"),
+			store.MergePatch([]byte(`{"external": {"description": "stale"}}`)),
+			options.IfRevision(2))
+		Expect(errors.Is(err, constants.ErrConflict)).To(BeTrue())
+
+		_, err = patcher.Patch(ctx, generated.WorldIdentity("patched"),
+			store.JSONPatch([]byte(`[{"op": "remove", "path": "/external/missing"}]`)))
+		Expect(errors.Is(err, constants.ErrInvalidPatch)).To(BeTrue())
+
+		_, err = patcher.Patch(ctx, generated.WorldIdentity("patched"),
+			store.MergePatch([]byte(`{"external": {"nested": {"color": "purple"}}}`)))
+		Expect(err).ToNot(BeNil())
+
+		_, err = patcher.Patch(ctx, generated.WorldIdentity("unpatched"),
+			store.MergePatch([]byte(`{"external": {"description": "none"}}`)))
+		Expect(errors.Is(err, constants.ErrNoSuchObject)).To(BeTrue())
+
+		ret, err = clt.Get(ctx, generated.WorldIdentity("patched"))
+		Expect(err).To(BeNil())
+		Expect(ret.Metadata().Revision()).To(Equal(int64(3)))
+
+		err = clt.Delete(ctx, generated.WorldIdentity("patched"))
+		Expect(err).To(BeNil())
+	})
+
+	It("can WATCH object changes", func() {
+		watcher, ok := clt.(store.Watcher)
+		if !ok {
+			Skip("store does not support watching")
+		}
+
+		wctx, wcancel := context.WithCancel(ctx)
+		defer wcancel()
+
+		events, err := watcher.Watch(wctx, generated.WorldKindIdentity())
+		Expect(err).To(BeNil())
+
+		w := generated.WorldFactory()
+		w.External().SetName("watched")
+
+		ret, err := clt.Create(ctx, w)
+		Expect(err).To(BeNil())
+
+		w = ret.(generated.World)
+		w.External().SetDescription("changed")
+		_, err = clt.Update(ctx, w.Metadata().Identity(), w)
+		Expect(err).To(BeNil())
+
+		err = clt.Delete(ctx, w.Metadata().Identity())
+		Expect(err).To(BeNil())
+
+		ev := <-events
+		Expect(ev.Type).To(Equal(store.EventCreated))
+		Expect(ev.Object.PrimaryKey()).To(Equal("watched"))
+
+		ev = <-events
+		Expect(ev.Type).To(Equal(store.EventUpdated))
+		Expect(ev.Object.(generated.World).External().Description()).To(Equal("changed"))
+
+		ev = <-events
+		Expect(ev.Type).To(Equal(store.EventDeleted))
+		Expect(ev.Object.Metadata().Identity()).To(Equal(w.Metadata().Identity()))
+
+		wcancel()
+		Eventually(events).Should(BeClosed())
+	})
+
 })
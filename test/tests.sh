@@ -8,4 +8,5 @@ go test -ginkgo.v -args store=react
 go test -ginkgo.v -args store=sqlite
 go test -ginkgo.v -args store=client
 # go test -ginkgo.v -args store=mysql
+# go test -ginkgo.v -args store=postgres
 # go test -ginkgo.v -args store=mongo
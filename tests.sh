@@ -5,9 +5,12 @@ echo "########### STARTING TEST SUITE #############"
 ginkgo -r -focus "gostorz"
 ginkgo -r -focus "mgen"
 
+ginkgo -r -race -focus "memory"
+ginkgo -r -focus "sql"
 ginkgo -r -focus "cache"
 ginkgo -r -focus "react"
 ginkgo -r -focus "client"
+ginkgo -r -race -focus "controller"
 
 cd test
 ./tests.sh
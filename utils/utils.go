@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/Jeffail/gabs"
+	"github.com/wazofski/gostorz/filter"
 	"github.com/wazofski/gostorz/internal/constants"
 	"github.com/wazofski/gostorz/store"
 )
@@ -110,6 +111,26 @@ func ObjectPath(obj store.Object, path string) *string {
 	return &ret
 }
 
+// ObjectValue returns the JSON value at the path, nil when missing
+func ObjectValue(obj store.Object, path string) interface{} {
+	data, _ := json.Marshal(obj)
+	jsn, err := gabs.ParseJSON(data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return jsn.Path(path).Data()
+}
+
+func FilterKeysExist(obj store.Object, expr *filter.Expression) bool {
+	for _, k := range expr.Keys() {
+		if ObjectPath(obj, k) == nil {
+			return false
+		}
+	}
+	return true
+}
+
 func ExportFile(targetDir string, name string, content string) error {
 	os.Mkdir(targetDir, 0755)
 
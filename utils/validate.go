@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/wazofski/gostorz/store"
+)
+
+// Constraint holds the model rules of a property.
+// Empty strings, lists and maps only fail Required.
+type Constraint struct {
+	Required  bool
+	Min       *float64
+	Max       *float64
+	MinLength *int
+	MaxLength *int
+	Pattern   *regexp.Regexp
+	Enum      []string
+}
+
+// ValidateProperty checks the value against the constraint
+func ValidateProperty(path string, value interface{}, c Constraint) error {
+	v := reflect.ValueOf(value)
+
+	length := -1
+	switch v.Kind() {
+	case reflect.String:
+		length = utf8.RuneCountInString(v.String())
+	case reflect.Slice, reflect.Map:
+		length = v.Len()
+	}
+
+	if length == 0 {
+		if c.Required {
+			return invalid(path, "is required")
+		}
+		return nil
+	}
+
+	if c.Min != nil || c.Max != nil {
+		num, ok := number(v)
+		if ok && c.Min != nil && num < *c.Min {
+			return invalid(path, fmt.Sprintf("must be at least %v", *c.Min))
+		}
+		if ok && c.Max != nil && num > *c.Max {
+			return invalid(path, fmt.Sprintf("must be at most %v", *c.Max))
+		}
+	}
+
+	if c.MinLength != nil && length >= 0 && length < *c.MinLength {
+		return invalid(path, fmt.Sprintf("must be at least %d long", *c.MinLength))
+	}
+
+	if c.MaxLength != nil && length > *c.MaxLength {
+		return invalid(path, fmt.Sprintf("must be at most %d long", *c.MaxLength))
+	}
+
+	if c.Pattern != nil && v.Kind() == reflect.String &&
+		!c.Pattern.MatchString(v.String()) {
+		return invalid(path, fmt.Sprintf("must match %s", c.Pattern))
+	}
+
+	if len(c.Enum) > 0 {
+		str := fmt.Sprint(value)
+		for _, e := range c.Enum {
+			if e == str {
+				return nil
+			}
+		}
+		return invalid(path, fmt.Sprintf("must be one of %v", c.Enum))
+	}
+
+	return nil
+}
+
+// ValidateNested prefixes the property path of nested validation errors
+func ValidateNested(path string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	verr := &store.ValidationError{}
+	if errors.As(err, &verr) {
+		return invalid(path+"."+verr.Path, verr.Reason)
+	}
+
+	return err
+}
+
+func Float(v float64) *float64 {
+	return &v
+}
+
+func Int(v int) *int {
+	return &v
+}
+
+func invalid(path string, reason string) error {
+	return &store.ValidationError{
+		Path:   path,
+		Reason: reason,
+	}
+}
+
+func number(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+
+	return 0, false
+}